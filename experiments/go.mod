@@ -1,17 +1,23 @@
 module github.com/pcolladosoto/mlp-go/experiments
 
-go 1.17
+go 1.19
 
-replace github.com/pcolladosoto/mlp-go/mlp => /Users/collado/Repos/stuff/mlp-go/mlp
+replace github.com/pcolladosoto/mlp-go/mlp => ../mlp
 
 require (
 	github.com/pcolladosoto/mlp-go/mlp v0.0.0-00010101000000-000000000000
 	github.com/spf13/cobra v1.4.0
+	google.golang.org/grpc v1.64.1
+	google.golang.org/protobuf v1.33.0
 )
 
 require (
 	github.com/inconshreveable/mousetrap v1.0.0 // indirect
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
 	gonum.org/v1/gonum v0.11.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240318140521-94a12d6c2237 // indirect
 )
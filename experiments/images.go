@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/plot"
+)
+
+func init() {
+	imagesCmd.Flags().StringVar(&labelsPath, "labels", "", "The MNIST label file to draw the labels from, if any.")
+	imagesCmd.Flags().StringVar(&predictModel, "model", "",
+		"A saved MLP whose predictions are drawn next to the labels, if any: green when right and red when wrong.")
+	imagesCmd.Flags().IntVar(&imgFrom, "from", 0, "The index of the first image to export.")
+	imagesCmd.Flags().IntVar(&imgTo, "to", 1, "The index past the last image to export.")
+	imagesCmd.Flags().IntVar(&sheetCols, "cols", 10, "The number of images on each row of the contact sheet.")
+	imagesCmd.Flags().UintVar(&imgOpts.Width, "width", mlp.DefaultImageOptions.Width, "The width of each image in pixels.")
+	imagesCmd.Flags().UintVar(&imgOpts.Height, "height", mlp.DefaultImageOptions.Height, "The height of each image in pixels.")
+	imagesCmd.Flags().StringVar(&imgOpts.Interpolation, "interpolation", mlp.DefaultImageOptions.Interpolation,
+		"How to resize the images. One of: [nearest, bilinear, bicubic, mitchell, lanczos2, lanczos3].")
+	imagesCmd.Flags().BoolVar(&imgOpts.Invert, "invert", mlp.DefaultImageOptions.Invert,
+		"Whether to draw the digits in black over a white background.")
+}
+
+var (
+	labelsPath   string
+	predictModel string
+	imgFrom      int
+	imgTo        int
+	sheetCols    int
+	imgOpts      mlp.ImageOptions
+
+	imagesCmd = &cobra.Command{
+		Use:   "images <MNIST image file> <output PNG>",
+		Short: "Export MNIST images as PNG files.",
+		Long: "This command exports the images in [--from, --to) of an MNIST image file as a PNG contact sheet,\n" +
+			"drawing the labels and the predictions of a saved MLP beneath each image if asked to.\n" +
+			"A single image without labels nor predictions is exported on its own.\n",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("you need to provide both the MNIST image file and the output PNG")
+			}
+			if _, ok := mlp.Interpolations[strings.ToLower(imgOpts.Interpolation)]; !ok {
+				return fmt.Errorf("unknown interpolation %s", imgOpts.Interpolation)
+			}
+			if imgFrom < 0 || imgTo <= imgFrom {
+				return fmt.Errorf("the range of images [%d, %d) is empty", imgFrom, imgTo)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors from here on aren't usage errors, and main reports them
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			imgs, err := mlp.ReadImgs(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't read the images: %v", err)
+			}
+
+			if imgTo == imgFrom+1 && labelsPath == "" && predictModel == "" {
+				if err := mlp.DumpImageWith(imgs, imgFrom, args[1], imgOpts); err != nil {
+					return fmt.Errorf("couldn't export the image: %v", err)
+				}
+				return nil
+			}
+
+			opts := plot.SheetOptions{ImageOptions: imgOpts, From: imgFrom, To: imgTo, Cols: sheetCols}
+
+			var labels *mlp.Labels
+			if labelsPath != "" {
+				lbs, err := mlp.ReadLabels(labelsPath)
+				if err != nil {
+					return fmt.Errorf("couldn't read the labels: %v", err)
+				}
+				labels = &lbs
+			}
+
+			if predictModel != "" {
+				m, err := mlp.Load(predictModel)
+				if err != nil {
+					return fmt.Errorf("couldn't load the MLP: %v", err)
+				}
+				if opts.Predicted, err = mlp.PredictClasses(m, imgs, imgFrom, imgTo); err != nil {
+					return fmt.Errorf("couldn't predict the labels: %v", err)
+				}
+			}
+
+			if err := plot.DumpContactSheet(imgs, labels, args[1], opts); err != nil {
+				return fmt.Errorf("couldn't export the contact sheet: %v", err)
+			}
+			return nil
+		},
+	}
+)
@@ -0,0 +1,6 @@
+// Package onnxpb holds the Go types generated from a subset of onnx.proto so
+// tests can check the models the mlp package reads and writes against the
+// ONNX schema rather than against its own decoder.
+package onnxpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative onnx.proto
@@ -0,0 +1,248 @@
+package onnxpb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+// checkKnownFields fails if any message within m carries fields onnx.proto
+// doesn't define, which a wrong field number or wire type would leave behind.
+func checkKnownFields(t *testing.T, m protoreflect.Message) {
+	t.Helper()
+	if unknown := m.GetUnknown(); len(unknown) > 0 {
+		t.Errorf("%s has unknown fields: %x", m.Descriptor().FullName(), unknown)
+	}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.Message() == nil:
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				checkKnownFields(t, v.List().Get(i).Message())
+			}
+		default:
+			checkKnownFields(t, v.Message())
+		}
+		return true
+	})
+}
+
+// decodeRaw decodes the little-endian raw_data of a tensor.
+func decodeRaw(t *testing.T, tensor *TensorProto) []float64 {
+	t.Helper()
+	raw := tensor.GetRawData()
+	var vs []float64
+	switch TensorProto_DataType(tensor.GetDataType()) {
+	case TensorProto_FLOAT:
+		for ; len(raw) >= 4; raw = raw[4:] {
+			vs = append(vs, float64(math.Float32frombits(binary.LittleEndian.Uint32(raw))))
+		}
+	case TensorProto_DOUBLE:
+		for ; len(raw) >= 8; raw = raw[8:] {
+			vs = append(vs, math.Float64frombits(binary.LittleEndian.Uint64(raw)))
+		}
+	default:
+		t.Fatalf("tensor %s holds data of type %d", tensor.GetName(), tensor.GetDataType())
+	}
+	if len(raw) != 0 {
+		t.Errorf("tensor %s has %d trailing bytes", tensor.GetName(), len(raw))
+	}
+	return vs
+}
+
+func newMlp(t *testing.T, dims []int, act mlp.Activation) *mlp.Mlp {
+	t.Helper()
+	m, err := mlp.NewMlpWith(dims, act, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	return m
+}
+
+func TestExportedModel(t *testing.T) {
+	normed := newMlp(t, []int{2, 3, 3, 1}, mlp.ReLuAct)
+	if err := normed.SetNorm(0, mlp.BatchNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+	if err := normed.SetNorm(1, mlp.LayerNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		m        *mlp.Mlp
+		opts     mlp.ONNXOptions
+		ops      []string
+		opset    int64
+		elemType TensorProto_DataType
+	}{
+		{"gemm", newMlp(t, []int{3, 4, 2}, mlp.SigmoidAct), mlp.ONNXOptions{}, []string{"Gemm", "Sigmoid", "Gemm", "Sigmoid"}, 13, TensorProto_DOUBLE},
+		{"matmul", newMlp(t, []int{3, 4, 2}, mlp.SigmoidAct), mlp.ONNXOptions{MatMul: true, Softmax: true, Float32: true},
+			[]string{"MatMul", "Add", "Sigmoid", "MatMul", "Add", "Sigmoid", "Softmax"}, 13, TensorProto_FLOAT},
+		{"norms", normed, mlp.ONNXOptions{},
+			[]string{"Gemm", "BatchNormalization", "Relu", "Gemm", "LayerNormalization", "Relu", "Gemm", "Relu"}, 17, TensorProto_DOUBLE},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.m.MarshalONNX(tc.opts)
+			if err != nil {
+				t.Fatalf("MarshalONNX() returned an error: %v", err)
+			}
+			var model ModelProto
+			if err := proto.Unmarshal(data, &model); err != nil {
+				t.Fatalf("couldn't decode the model against onnx.proto: %v", err)
+			}
+			checkKnownFields(t, model.ProtoReflect())
+
+			if len(model.GetOpsetImport()) != 1 || model.GetOpsetImport()[0].GetVersion() != tc.opset || model.GetOpsetImport()[0].GetDomain() != "" {
+				t.Errorf("wrong opsets: %v", model.GetOpsetImport())
+			}
+			if model.GetProducerName() != "mlp-go" {
+				t.Errorf("wrong producer: %q", model.GetProducerName())
+			}
+
+			g := model.GetGraph()
+			var ops []string
+			for _, n := range g.GetNode() {
+				ops = append(ops, n.GetOpType())
+			}
+			if !reflect.DeepEqual(ops, tc.ops) {
+				t.Errorf("got nodes %v, want %v", ops, tc.ops)
+			}
+			if out := g.GetNode()[len(g.GetNode())-1].GetOutput(); len(out) != 1 || out[0] != "output" {
+				t.Errorf("the last node produces %v instead of output", out)
+			}
+
+			for _, vi := range append(g.GetInput(), g.GetOutput()...) {
+				tt := vi.GetType().GetTensorType()
+				if TensorProto_DataType(tt.GetElemType()) != tc.elemType {
+					t.Errorf("%s has elements of type %d, want %d", vi.GetName(), tt.GetElemType(), tc.elemType)
+				}
+				dims := tt.GetShape().GetDim()
+				if len(dims) != 2 || dims[0].GetDimParam() != "batch" || dims[1].GetDimValue() == 0 {
+					t.Errorf("%s has the wrong shape: %v", vi.GetName(), dims)
+				}
+			}
+
+			tensors := map[string]*TensorProto{}
+			for _, tensor := range g.GetInitializer() {
+				if TensorProto_DataType(tensor.GetDataType()) != tc.elemType {
+					t.Errorf("tensor %s holds data of type %d, want %d", tensor.GetName(), tensor.GetDataType(), tc.elemType)
+				}
+				tensors[tensor.GetName()] = tensor
+			}
+			for i, w := range tc.m.Weights {
+				out, in := w.Dims()
+				in--
+				weight, bias := tensors[fmt.Sprintf("dense%d.weight", i)], tensors[fmt.Sprintf("dense%d.bias", i)]
+				if weight == nil || bias == nil {
+					t.Fatalf("missing the parameters of layer %d", i)
+				}
+				wantDims := []int64{int64(out), int64(in)}
+				if tc.opts.MatMul {
+					wantDims = []int64{int64(in), int64(out)}
+				}
+				if !reflect.DeepEqual(weight.GetDims(), wantDims) || !reflect.DeepEqual(bias.GetDims(), []int64{int64(out)}) {
+					t.Errorf("layer %d has weights of shape %v and biases of shape %v", i, weight.GetDims(), bias.GetDims())
+				}
+
+				ws, bs := decodeRaw(t, weight), decodeRaw(t, bias)
+				for j := 0; j < out; j++ {
+					for k := 0; k <= in; k++ {
+						got := bs[j]
+						if k < in {
+							got = ws[j*in+k]
+							if tc.opts.MatMul {
+								got = ws[k*out+j]
+							}
+						}
+						want := w.At(j, k)
+						if tc.opts.Float32 {
+							want = float64(float32(want))
+						}
+						if got != want {
+							t.Errorf("layer %d: parameter (%d, %d) is %g, want %g", i, j, k, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestImportModel(t *testing.T) {
+	// A 2-3-1 MLP written the way other producers do, with the weights
+	// in the typed fields instead of raw_data
+	weights := [][]float32{{0.5, -1, 0.25, 2, 1.5, -0.5}, {1, -2, 0.75}}
+	biases := [][]float32{{0.1, 0.2, 0.3}, {-0.4}}
+	dims := []int64{2, 3, 1}
+
+	dim := func(v int64) *TensorShapeProto_Dimension {
+		return &TensorShapeProto_Dimension{Value: &TensorShapeProto_Dimension_DimValue{DimValue: v}}
+	}
+	valueInfo := func(name string, d int64) *ValueInfoProto {
+		return &ValueInfoProto{Name: proto.String(name), Type: &TypeProto{Value: &TypeProto_TensorType{TensorType: &TypeProto_Tensor{
+			ElemType: proto.Int32(int32(TensorProto_FLOAT)),
+			Shape: &TensorShapeProto{Dim: []*TensorShapeProto_Dimension{
+				{Value: &TensorShapeProto_Dimension_DimParam{DimParam: "N"}}, dim(d),
+			}},
+		}}}}
+	}
+
+	g := &GraphProto{Name: proto.String("external"), Input: []*ValueInfoProto{valueInfo("x", 2)}, Output: []*ValueInfoProto{valueInfo("y", 1)}}
+	x := "x"
+	for i := range weights {
+		w, b, h, out := fmt.Sprintf("w%d", i), fmt.Sprintf("b%d", i), fmt.Sprintf("h%d", i), fmt.Sprintf("a%d", i)
+		if i == len(weights)-1 {
+			out = "y"
+		}
+		g.Initializer = append(g.Initializer,
+			&TensorProto{Name: proto.String(w), Dims: []int64{dims[i+1], dims[i]}, DataType: proto.Int32(int32(TensorProto_FLOAT)), FloatData: weights[i]},
+			&TensorProto{Name: proto.String(b), Dims: []int64{dims[i+1]}, DataType: proto.Int32(int32(TensorProto_FLOAT)), FloatData: biases[i]},
+		)
+		g.Node = append(g.Node,
+			&NodeProto{Name: proto.String(h), OpType: proto.String("Gemm"), Input: []string{x, w, b}, Output: []string{h},
+				Attribute: []*AttributeProto{{Name: proto.String("transB"), Type: AttributeProto_INT.Enum(), I: proto.Int64(1)}}},
+			&NodeProto{OpType: proto.String("Sigmoid"), Input: []string{h}, Output: []string{out}},
+		)
+		x = out
+	}
+	data, err := proto.Marshal(&ModelProto{
+		IrVersion:    proto.Int64(7),
+		OpsetImport:  []*OperatorSetIdProto{{Version: proto.Int64(13)}},
+		ProducerName: proto.String("onnxpb"),
+		Graph:        g,
+	})
+	if err != nil {
+		t.Fatalf("couldn't encode the model: %v", err)
+	}
+
+	m, err := mlp.UnmarshalONNX(data)
+	if err != nil {
+		t.Fatalf("UnmarshalONNX() returned an error: %v", err)
+	}
+	if m.InDim != 2 || m.OutDim != 1 || !reflect.DeepEqual(m.HiddenDim, []int{3}) || m.ActFunc.Name != mlp.SigmoidAct.Name {
+		t.Fatalf("got a %d-%v-%d MLP with %s activations", m.InDim, m.HiddenDim, m.OutDim, m.ActFunc.Name)
+	}
+	for i, w := range m.Weights {
+		out, in := w.Dims()
+		in--
+		for j := 0; j < out; j++ {
+			for k := 0; k < in; k++ {
+				if got, want := w.At(j, k), float64(weights[i][j*in+k]); got != want {
+					t.Errorf("layer %d: weight (%d, %d) is %g, want %g", i, j, k, got, want)
+				}
+			}
+			if got, want := w.At(j, in), float64(biases[i][j]); got != want {
+				t.Errorf("layer %d: bias %d is %g, want %g", i, j, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package mlprpc serves the predictions of an MLP over gRPC as defined in
+// predictor.proto. The messages, the client and the service description are
+// generated with protoc-gen-go and protoc-gen-go-grpc: run go generate after
+// changing predictor.proto.
+package mlprpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative predictor.proto
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/serve"
+)
+
+// Server implements PredictorServer with the model held by a serve.ModelStore.
+type Server struct {
+	UnimplementedPredictorServer
+
+	// MaxBatch bounds the number of inputs in batch requests.
+	MaxBatch int
+
+	store *serve.ModelStore
+}
+
+// NewServer serves a snapshot of the MLP taken through Predictor.
+func NewServer(m *mlp.Mlp) *Server {
+	return NewStoreServer(serve.NewModelStore(m))
+}
+
+// NewStoreServer serves the model in the store, picking up the new ones
+// swapped in.
+func NewStoreServer(store *serve.ModelStore) *Server {
+	return &Server{MaxBatch: 1024, store: store}
+}
+
+func (s *Server) Predict(ctx context.Context, req *PredictRequest) (*PredictResponse, error) {
+	predictor, _ := s.store.Get()
+	output, err := predictor.Predict(req.Input)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &PredictResponse{Output: output, Class: int32(mlp.PredictedClass(output))}, nil
+}
+
+func (s *Server) BatchPredict(ctx context.Context, req *BatchPredictRequest) (*BatchPredictResponse, error) {
+	if s.MaxBatch > 0 && len(req.Inputs) > s.MaxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "too many inputs: got %d, at most %d", len(req.Inputs), s.MaxBatch)
+	}
+
+	inputs := make([][]float64, len(req.Inputs))
+	for i, input := range req.Inputs {
+		inputs[i] = input.Input
+	}
+	predictor, _ := s.store.Get()
+	outputs, err := predictor.PredictBatch(inputs)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	resp := BatchPredictResponse{Outputs: make([]*PredictResponse, len(outputs))}
+	for i, output := range outputs {
+		resp.Outputs[i] = &PredictResponse{Output: output, Class: int32(mlp.PredictedClass(output))}
+	}
+	return &resp, nil
+}
+
+func (s *Server) ModelInfo(ctx context.Context, req *ModelInfoRequest) (*ModelInfoResponse, error) {
+	_, info := s.store.Get()
+	resp := ModelInfoResponse{Activation: info.Activation, Parameters: int64(info.Parameters)}
+	for _, d := range info.Dims {
+		resp.Dims = append(resp.Dims, int32(d))
+	}
+	return &resp, nil
+}
+
+// MetricsInterceptor tracks the requests handled by a gRPC server, using the
+// full method names as endpoints and the status codes as codes.
+func MetricsInterceptor(m *serve.Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.ObserveRequest(info.FullMethod, status.Code(err).String(), time.Since(start).Seconds())
+		if err == nil {
+			switch req := req.(type) {
+			case *PredictRequest:
+				m.Predictions.Inc()
+			case *BatchPredictRequest:
+				m.Predictions.Add(float64(len(req.Inputs)))
+			}
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,133 @@
+package mlprpc
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/metrics"
+	"github.com/pcolladosoto/mlp-go/mlp/serve"
+)
+
+// newTestConn serves the MLP over an in-memory connection, together with the
+// standard health service.
+func newTestConn(t *testing.T, m *mlp.Mlp) *grpc.ClientConn {
+	l := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterPredictorServer(s, NewServer(m))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("couldn't dial the server: %v", err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestPredictorService(t *testing.T) {
+	m, err := mlp.NewMlpWith([]int{3, 4, 2}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	c := NewPredictorClient(newTestConn(t, m))
+	ctx := context.Background()
+	inputs := [][]float64{{0.1, 0.2, 0.3}, {-1, 0, 1}}
+
+	resp, err := c.Predict(ctx, &PredictRequest{Input: inputs[0]})
+	if err != nil {
+		t.Fatalf("Predict() returned an error: %v", err)
+	}
+	want, _, _ := m.ComputeActivation(inputs[0])
+	if !reflect.DeepEqual(resp.Output, want) || int(resp.Class) != mlp.PredictedClass(want) {
+		t.Errorf("wrong prediction: %+v, expected %v", resp, want)
+	}
+
+	batch, err := c.BatchPredict(ctx, &BatchPredictRequest{Inputs: []*PredictRequest{{Input: inputs[0]}, {Input: inputs[1]}}})
+	if err != nil {
+		t.Fatalf("BatchPredict() returned an error: %v", err)
+	}
+	if len(batch.Outputs) != 2 {
+		t.Fatalf("got %d outputs for 2 inputs", len(batch.Outputs))
+	}
+	for i, input := range inputs {
+		want, _, _ := m.ComputeActivation(input)
+		if !reflect.DeepEqual(batch.Outputs[i].Output, want) || int(batch.Outputs[i].Class) != mlp.PredictedClass(want) {
+			t.Errorf("wrong prediction for input %d: %+v, expected %v", i, batch.Outputs[i], want)
+		}
+	}
+
+	info, err := c.ModelInfo(ctx, &ModelInfoRequest{})
+	if err != nil {
+		t.Fatalf("ModelInfo() returned an error: %v", err)
+	}
+	if !reflect.DeepEqual(info.Dims, []int32{3, 4, 2}) || info.Activation != "sigmoid" || info.Parameters != 4*4+2*5 {
+		t.Errorf("wrong model info: %+v", info)
+	}
+
+	if _, err := c.Predict(ctx, &PredictRequest{Input: []float64{1}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Predict() accepted an input of the wrong dimension: %v", err)
+	}
+	if _, err := c.BatchPredict(ctx, &BatchPredictRequest{Inputs: []*PredictRequest{{Input: inputs[0]}, {}}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("BatchPredict() accepted an empty input: %v", err)
+	}
+}
+
+func TestHealthService(t *testing.T) {
+	m, err := mlp.NewMlpWith([]int{2, 2, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	resp, err := healthpb.NewHealthClient(newTestConn(t, m)).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() returned an error: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("wrong health status: %v", resp.Status)
+	}
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	intercept := MetricsInterceptor(serve.NewMetrics(r))
+	info := &grpc.UnaryServerInfo{FullMethod: "/mlp.Predictor/BatchPredict"}
+	req := &BatchPredictRequest{Inputs: []*PredictRequest{{}, {}}}
+
+	intercept(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &BatchPredictResponse{}, nil
+	})
+	intercept(context.Background(), req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "no")
+	})
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() returned an error: %v", err)
+	}
+	for _, want := range []string{
+		`mlp_requests_total{endpoint="/mlp.Predictor/BatchPredict",code="OK"} 1`,
+		`mlp_requests_total{endpoint="/mlp.Predictor/BatchPredict",code="InvalidArgument"} 1`,
+		`mlp_request_duration_seconds_count{endpoint="/mlp.Predictor/BatchPredict"} 2`,
+		`mlp_predictions_total 2`,
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("the metrics lack %q:\n%s", want, b.String())
+		}
+	}
+}
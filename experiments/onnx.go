@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func init() {
+	exportCmd.Flags().BoolVar(&onnxOpts.MatMul, "matmul", false, "Map each layer's inputs through MatMul and Add nodes instead of a Gemm one.")
+	exportCmd.Flags().BoolVar(&onnxOpts.Softmax, "softmax", false, "Append a Softmax node turning the outputs into class probabilities.")
+	exportCmd.Flags().BoolVar(&onnxOpts.Float32, "float32", false, "Store the parameters and take the inputs as 32-bit floats instead of 64-bit ones.")
+}
+
+var (
+	onnxOpts mlp.ONNXOptions
+
+	exportCmd = &cobra.Command{
+		Use:   "export <saved MLP> <ONNX file>",
+		Short: "Export a saved MLP as an ONNX model.",
+		Long: "This command loads a saved MLP and writes it as an ONNX model taking an input tensor named input\n" +
+			"of shape [batch, input dimension] and producing an output one named output of shape\n" +
+			"[batch, output dimension], so that it can be run through any ONNX runtime.\n",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("you need to provide the saved MLP and where to write the ONNX model")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors from here on aren't usage errors, and main reports them
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			m, err := mlp.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't load the MLP: %v", err)
+			}
+			if err := m.ExportONNX(args[1], onnxOpts); err != nil {
+				return fmt.Errorf("couldn't export the MLP: %v", err)
+			}
+			fmt.Printf("Exported %s to %s\n", args[0], args[1])
+			return nil
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:   "import <ONNX file> <saved MLP>",
+		Short: "Import an MLP from an ONNX model.",
+		Long: "This command reads an ONNX model made up of a chain of layers and saves it as an MLP that can be\n" +
+			"trained further or served. Each layer should be a Gemm node, or a MatMul one followed by an Add,\n" +
+			"optionally normalised through a BatchNormalization or LayerNormalization node and followed by\n" +
+			"the activation function, which must be the same for every layer: Sigmoid, Relu or Relu(Sign(x)).\n",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("you need to provide the ONNX model and where to save the MLP")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors from here on aren't usage errors, and main reports them
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			m, err := mlp.ImportONNX(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't import the MLP: %v", err)
+			}
+			if err := m.Save(args[1]); err != nil {
+				return fmt.Errorf("couldn't save the MLP: %v", err)
+			}
+			fmt.Printf("%s", m)
+			fmt.Printf("\nSaved the MLP to %s\n", args[1])
+			return nil
+		},
+	}
+)
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func init() {
+	predictCmd.Flags().IntVar(&imgRows, "rows", 28, "The number of rows of the images the MLP was trained on.")
+	predictCmd.Flags().IntVar(&imgCols, "cols", 28, "The number of columns of the images the MLP was trained on.")
+}
+
+var (
+	imgRows int
+	imgCols int
+
+	predictCmd = &cobra.Command{
+		Use:   "predict <saved MLP> <image>...",
+		Short: "Classify your own handwritten digits with a trained MLP.",
+		Long: "This command loads a saved MLP trained on MNIST-like data and prints the probability of each class\n" +
+			"for each of the given PNG or JPEG images. The digit in each image is cropped, scaled and centred\n" +
+			"just like those in MNIST before being classified. Both light digits over a dark background and\n" +
+			"dark digits over a light one are fine.\n",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 {
+				return fmt.Errorf("you need to provide a saved MLP and at least an image")
+			}
+			if imgRows <= 0 || imgCols <= 0 {
+				return fmt.Errorf("the images should have at least a row and a column")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors from here on aren't usage errors, and main reports them
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			m, err := mlp.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't load the MLP: %v", err)
+			}
+			if m.InDim != imgRows*imgCols {
+				return fmt.Errorf("the MLP takes %d inputs but the images have %d x %d pixels", m.InDim, imgRows, imgCols)
+			}
+			p := m.Predictor()
+
+			failed := false
+			for _, fpath := range args[1:] {
+				img, err := mlp.ReadCustomImg(fpath, imgRows, imgCols)
+				if err != nil {
+					fmt.Printf("%s: %v\n", fpath, err)
+					failed = true
+					continue
+				}
+
+				output, err := p.Predict(mlp.Flatten(img))
+				if err != nil {
+					fmt.Printf("%s: %v\n", fpath, err)
+					failed = true
+					continue
+				}
+
+				fmt.Printf("%s: predicted %d\n", fpath, mlp.PredictedClass(output))
+				for class, prob := range mlp.ClassProbabilities(output) {
+					fmt.Printf("\t%d -> %6.4f\n", class, prob)
+				}
+			}
+
+			if failed {
+				return fmt.Errorf("couldn't classify every image")
+			}
+			return nil
+		},
+	}
+)
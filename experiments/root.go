@@ -2,11 +2,16 @@ package main
 
 import (
 	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/metrics"
 )
 
 var (
@@ -14,12 +19,10 @@ var (
 	actFunction    string
 	weightVariance float64
 	learningRate   float64
-
-	actFuncMap map[string]func(float64) float64 = map[string]func(float64) float64{
-		"sigmoid":  mlp.Sigmoid,
-		"relu":     mlp.ReLu,
-		"unitstep": mlp.UnitStep,
-	}
+	regularization mlp.Regularization
+	gradClip       mlp.GradClip
+	dropoutRates   []float64
+	normKinds      []string
 
 	rootCmd = &cobra.Command{
 		Use:   "mlp-experiment",
@@ -27,7 +30,7 @@ var (
 		Long: "This executable implements some sample experiments driving the MLP implemented on github.com/pcolladosoto/mlp-go.\n" +
 			"Each available experiment is provided through a sub-command.\n",
 		Args: func(cmd *cobra.Command, args []string) error {
-			if _, ok := actFuncMap[strings.ToLower(actFunction)]; !ok {
+			if _, ok := mlp.Activations[strings.ToLower(actFunction)]; !ok {
 				return fmt.Errorf("wrong activation function %s: choose one of [sigmoid, ReLu, unitStep]", actFunction)
 			}
 			return nil
@@ -39,7 +42,7 @@ func init() {
 	// Disable Cobra completions
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
-	rootCmd.AddCommand(xorExp)
+	rootCmd.AddCommand(xorExp, imagesCmd, predictCmd, serveCmd, exportCmd, importCmd)
 
 	rootCmd.PersistentFlags().IntSliceVar(&mlpDims, "mlp_dimensions", []int{2, 2, 1},
 		"The dimension of each layer of the MLP. Note the initial and final dimensions are those of the input and output, respectively.")
@@ -49,4 +52,71 @@ func init() {
 		"The variance for the random and normally-distributed initial weights.")
 	rootCmd.PersistentFlags().Float64Var(&learningRate, "learning_rate", 0.05,
 		"The learning rate for the back-propagation algorithm.")
+	rootCmd.PersistentFlags().Float64Var(&regularization.L1, "l1", 0,
+		"The weight of the L1 penalty on the weights. Combine it with --l2 for an elastic-net penalty.")
+	rootCmd.PersistentFlags().Float64Var(&regularization.L2, "l2", 0,
+		"The weight of the L2 penalty (i.e. weight decay) on the weights.")
+	rootCmd.PersistentFlags().Float64Var(&regularization.MaxNorm, "max_norm", 0,
+		"The maximum L2 norm of the weights feeding each neuron. It's not enforced when 0.")
+	rootCmd.PersistentFlags().BoolVar(&regularization.ExcludeBias, "exclude_bias", false,
+		"Whether to leave the biases out of the penalties and the max-norm constraint.")
+	rootCmd.PersistentFlags().Float64Var(&gradClip.Value, "clip_value", 0,
+		"Clamp each gradient entry to [-clip_value, clip_value] before updating the weights. It's not enforced when 0.")
+	rootCmd.PersistentFlags().Float64Var(&gradClip.Norm, "clip_norm", 0,
+		"The maximum global L2 norm of the gradients before updating the weights. It's not enforced when 0.")
+	rootCmd.PersistentFlags().Float64SliceVar(&dropoutRates, "dropout", nil,
+		"The dropout rate for each hidden layer's output while training. Layers without one don't drop any neurons.")
+	rootCmd.PersistentFlags().StringSliceVar(&normKinds, "norm", nil,
+		"The normalisation of each hidden layer's net activations. One of: [none, batch, layer].")
+}
+
+// setNorms normalises the hidden layers as requested through --norm.
+func setNorms(m *mlp.Mlp) error {
+	for i, name := range normKinds {
+		var kind mlp.NormKind
+		if err := kind.UnmarshalText([]byte(strings.ToLower(name))); err != nil {
+			return err
+		}
+		if err := m.SetNorm(i, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeHistory writes the training history as CSV if fpath ends in .csv and
+// as JSON lines otherwise.
+func writeHistory(history *mlp.History, fpath string) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(fpath)) == ".csv" {
+		err = history.WriteCSV(f)
+	} else {
+		err = history.WriteJSONLines(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// serveMetrics serves the metrics in the registry on addr under /metrics in
+// the background until the process exits, logging why it stops if it does.
+func serveMetrics(addr string, registry *metrics.Registry) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	go func() {
+		if err := http.Serve(l, mux); err != nil {
+			fmt.Printf("stopped serving the metrics: %v\n", err)
+		}
+	}()
+	return nil
 }
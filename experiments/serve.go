@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/pcolladosoto/mlp-go/experiments/mlprpc"
+	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/serve"
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "The address to listen on.")
+	serveCmd.Flags().Int64Var(&maxBodyBytes, "max_body_bytes", 1<<20, "The maximum size of a request body in bytes.")
+	serveCmd.Flags().IntVar(&maxBatch, "max_batch", 1024, "The maximum number of inputs in a batch prediction request.")
+	serveCmd.Flags().StringVar(&grpcAddr, "grpc_addr", "", "The address to serve the gRPC Predictor service on, if anywhere.")
+	serveCmd.Flags().DurationVar(&watchInterval, "watch", 0,
+		"How often to check the saved MLP for changes, reloading it when it changes. It's not watched when 0.")
+	serveCmd.Flags().BoolVar(&allowReload, "allow_reload", false, "Whether to reload the saved MLP on POST /v1/reload.")
+}
+
+var (
+	serveAddr    string
+	maxBodyBytes int64
+	maxBatch     int
+	grpcAddr     string
+
+	watchInterval time.Duration
+	allowReload   bool
+
+	serveCmd = &cobra.Command{
+		Use:   "serve <saved MLP>",
+		Short: "Serve the predictions of a saved MLP over HTTP.",
+		Long: "This command loads a saved MLP and serves its predictions as JSON over HTTP:\n" +
+			"\tPOST /v1/predict        {\"input\": [...]}         -> {\"output\": [...], \"class\": n}\n" +
+			"\tPOST /v1/predict/batch  {\"inputs\": [[...], ...]} -> {\"outputs\": [[...], ...], \"classes\": [...]}\n" +
+			"\tGET  /v1/model          the dimensions, activation function and number of parameters\n" +
+			"\tGET  /healthz           {\"status\": \"ok\"}\n" +
+			"\tGET  /metrics           request counts, latencies and predictions in the Prometheus format\n" +
+			"Passing --grpc_addr also serves the Predictor service defined in mlprpc/predictor.proto over gRPC.\n" +
+			"With --watch or --allow_reload, a new model saved to the same file is swapped in without downtime\n" +
+			"as long as it's valid: the current model keeps being served otherwise.\n" +
+			"It shuts down gracefully on SIGINT and SIGTERM, waiting for in-flight requests.\n",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("you just need to provide the saved MLP")
+			}
+			if maxBodyBytes <= 0 || maxBatch <= 0 {
+				return fmt.Errorf("both the maximum body size and batch size should be positive")
+			}
+			if watchInterval < 0 {
+				return fmt.Errorf("the watch interval can't be negative")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Errors from here on aren't usage errors, and main reports them
+			cmd.SilenceUsage, cmd.SilenceErrors = true, true
+
+			m, err := mlp.Load(args[0])
+			if err != nil {
+				return fmt.Errorf("couldn't load the MLP: %v", err)
+			}
+
+			srv := serve.NewServer(m)
+			srv.MaxBodyBytes, srv.MaxBatch = maxBodyBytes, maxBatch
+			if allowReload {
+				srv.ModelPath = args[0]
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if grpcAddr != "" {
+				l, err := net.Listen("tcp", grpcAddr)
+				if err != nil {
+					return fmt.Errorf("couldn't listen for gRPC: %v", err)
+				}
+				rpcSrv := mlprpc.NewStoreServer(srv.Store())
+				rpcSrv.MaxBatch = maxBatch
+				gs := grpc.NewServer(grpc.MaxRecvMsgSize(int(maxBodyBytes)),
+					grpc.UnaryInterceptor(mlprpc.MetricsInterceptor(srv.Metrics())))
+				mlprpc.RegisterPredictorServer(gs, rpcSrv)
+
+				go gs.Serve(l)
+				defer gs.GracefulStop()
+				fmt.Printf("Serving %s over gRPC on %s\n", args[0], grpcAddr)
+			}
+
+			if watchInterval > 0 {
+				go srv.Store().Watch(ctx, args[0], watchInterval, func(err error) {
+					if err != nil {
+						fmt.Printf("couldn't reload the MLP: %v\n", err)
+						return
+					}
+					fmt.Printf("Reloaded %s\n", args[0])
+				})
+			}
+
+			fmt.Printf("Serving %s on %s\n", args[0], serveAddr)
+			if err := srv.ListenAndServe(ctx, serveAddr); err != nil {
+				return fmt.Errorf("couldn't serve the MLP: %v", err)
+			}
+			fmt.Printf("Shut down\n")
+			return nil
+		},
+	}
+)
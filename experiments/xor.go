@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/metrics"
+	"github.com/pcolladosoto/mlp-go/mlp/plot"
 )
 
 func init() {
@@ -17,10 +20,27 @@ func init() {
 	xorExp.Flags().IntVar(&trainDataPercentage, "train_percentage", 90,
 		"Percentage of the total data to use for training in the [0, 100) interval. The rest is used for testing.")
 	xorExp.Flags().StringVar(&trainingMode, "training_mode", "online",
-		"How to treat data points used for training. Once of: [online, batch].")
+		"How to treat data points used for training. One of: [online, batch, async].")
+	xorExp.Flags().IntVar(&batchSize, "batch_size", 16, "The size of each mini-batch when training in batch mode.")
+	xorExp.Flags().IntVar(&trainingWorkers, "workers", 1,
+		"The number of goroutines training the MLP in batch and async modes. Note async training is nondeterministic.")
+	xorExp.Flags().Int64Var(&shuffleSeed, "seed", 1, "The seed used to shuffle the data when training in batch mode.")
 
 	xorExp.Flags().Float64Var(&xorStdDev, "std_deviation", 0.1,
 		"The standard deviation of the noise added to generated XOR data.")
+	xorExp.Flags().StringVar(&modelPath, "save", "", "Where to save the trained MLP, if anywhere.")
+	xorExp.Flags().BoolVar(&checkHealth, "check_health", false,
+		"Whether to check the activations, gradients and weights for NaN and Inf after every training step, stopping if any shows up.")
+	xorExp.Flags().StringVar(&dumpPath, "dump_state", "",
+		"Where to dump the state of the MLP when training diverges, if anywhere. It implies --check_health.")
+	xorExp.Flags().IntVar(&logEvery, "log_every", 0,
+		"The number of training steps between progress logs. They're logged at the end of every epoch when 0.")
+	xorExp.Flags().StringVar(&historyPath, "history", "",
+		"Where to write the training history, if anywhere: as CSV if the file ends in .csv and as JSON lines otherwise.")
+	xorExp.Flags().StringVar(&plotPath, "plot", "",
+		"Where to plot the training curves and the learned decision boundary over the test data, if anywhere: as PNG or SVG depending on the extension.")
+	xorExp.Flags().StringVar(&metricsAddr, "metrics_addr", "",
+		"The address to serve the training metrics on under /metrics in the Prometheus format while training, if anywhere.")
 }
 
 var (
@@ -28,14 +48,25 @@ var (
 	trainDataPercentage int
 	trainingMode        string
 	trainingPasses      int
+	batchSize           int
+	trainingWorkers     int
+	shuffleSeed         int64
 
-	xorStdDev float64
+	xorStdDev   float64
+	modelPath   string
+	checkHealth bool
+	dumpPath    string
+	logEvery    int
+	historyPath string
+	plotPath    string
+	metricsAddr string
 
 	xorExp = &cobra.Command{
 		Use:   "xor <training passes>",
 		Short: "Use a MLP to classify 2-dimensional XOR data points.",
 		Long: "This experiment generates XOR data and then trains the MLP on it.\n" +
-			"You MUST provide the number of training iterations as an argument. The rest'\n" +
+			"You MUST provide the number of training iterations as an argument: that's the number of samples\n" +
+			"in online mode and the number of epochs in batch and async modes. The rest\n" +
 			"of the parameters are configured through flags. Feel free to use `-h` to take a look!\n",
 		Args: func(cmd *cobra.Command, args []string) error {
 			if dataSize < 0 {
@@ -44,8 +75,25 @@ var (
 			if trainDataPercentage < 0 || trainDataPercentage > 100 {
 				return fmt.Errorf("the training data percentage should be within the [0, 100) interval")
 			}
-			if trainingMode != "online" && trainingMode != "batch" {
-				return fmt.Errorf("unsupported training mode %s. Choose either online or batch", trainingMode)
+			if trainingMode != "online" && trainingMode != "batch" && trainingMode != "async" {
+				return fmt.Errorf("unsupported training mode %s. Choose one of online, batch or async", trainingMode)
+			}
+			for _, rate := range dropoutRates {
+				if rate < 0 || rate >= 1 {
+					return fmt.Errorf("dropout rates should be within the [0, 1) interval")
+				}
+			}
+			if gradClip.Value < 0 || gradClip.Norm < 0 {
+				return fmt.Errorf("gradient clipping thresholds can't be negative")
+			}
+			if logEvery < 0 {
+				return fmt.Errorf("the number of steps between logs can't be negative")
+			}
+			if batchSize < 1 || trainingWorkers < 1 {
+				return fmt.Errorf("both the batch size and the number of workers should be at least 1")
+			}
+			if ext := strings.ToLower(filepath.Ext(plotPath)); plotPath != "" && ext != ".png" && ext != ".svg" {
+				return fmt.Errorf("can't tell the format of %s: use either a .png or a .svg extension", plotPath)
 			}
 
 			if len(args) != 1 {
@@ -60,11 +108,18 @@ var (
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			m, err := mlp.NewMlp(mlpDims, actFuncMap[strings.ToLower(actFunction)], weightVariance)
+			m, err := mlp.NewMlpWith(mlpDims, mlp.Activations[strings.ToLower(actFunction)], weightVariance)
 			if err != nil {
 				fmt.Printf("couldn't instantiate an MLP: %v\n", err)
 				os.Exit(-1)
 			}
+			m.Regularization = regularization
+			m.GradClip = gradClip
+			m.Dropout = dropoutRates
+			if err := setNorms(m); err != nil {
+				fmt.Printf("couldn't normalise the MLP: %v\n", err)
+				os.Exit(-1)
+			}
 			fmt.Printf("%s", m)
 
 			fmt.Printf("\nGenerating XOR data... ")
@@ -79,13 +134,99 @@ var (
 
 			var outputPredTest []float64
 
-			fmt.Printf("\nTraining the MLP... ")
-			for i := 0; i < trainingPasses; i++ {
-				rSample := rand.Intn(trainDataThreshold)
-				m.Adapt(xorDataTrain[rSample], []float64{xorLabelsTrain[rSample]}, learningRate)
+			var monitor *mlp.HealthMonitor
+			if checkHealth || dumpPath != "" {
+				monitor = &mlp.HealthMonitor{DumpPath: dumpPath}
+			}
+			checkStep := func() {
+				if monitor == nil {
+					return
+				}
+				if err := monitor.Check(m); err != nil {
+					fmt.Printf("%v\n", err)
+					os.Exit(-1)
+				}
+			}
+
+			xorTargetsTrain := toTargets(xorLabelsTrain)
+			rec := mlp.Recorder{
+				ValInputs: xorDataTest, ValTargets: toTargets(xorLabelsTest),
+				Metrics: []mlp.Metric{mlp.AccuracyMetric}, Every: logEvery, Log: os.Stdout,
+			}
+			rec.Start()
+
+			var trainingMetrics *mlp.TrainingMetrics
+			if metricsAddr != "" {
+				registry := metrics.NewRegistry()
+				trainingMetrics = mlp.NewTrainingMetrics(registry)
+				if err := serveMetrics(metricsAddr, registry); err != nil {
+					fmt.Printf("couldn't serve the training metrics: %v\n", err)
+					os.Exit(-1)
+				}
+				fmt.Printf("\nServing the training metrics on %s/metrics\n", metricsAddr)
+			}
+
+			// record takes a record every logEvery steps or at the end of each
+			// epoch if it's 0.
+			record := func(epoch, step, samples int, endOfEpoch bool) {
+				if (logEvery > 0 && step%logEvery == 0) || (logEvery == 0 && endOfEpoch) {
+					r := rec.Record(m, xorDataTrain, xorTargetsTrain, epoch, step, samples)
+					if trainingMetrics != nil {
+						trainingMetrics.ObserveRecord(r)
+					}
+				}
+			}
+
+			fmt.Printf("\nTraining the MLP...\n")
+			switch trainingMode {
+			case "online":
+				// An epoch goes through as many samples as there are in the
+				// training data.
+				for i := 1; i <= trainingPasses; i++ {
+					rSample := rand.Intn(trainDataThreshold)
+					m.Adapt(xorDataTrain[rSample], []float64{xorLabelsTrain[rSample]}, learningRate)
+					checkStep()
+					if trainingMetrics != nil {
+						trainingMetrics.ObserveStep(m, 1)
+					}
+					record((i-1)/trainDataThreshold+1, i, i, i%trainDataThreshold == 0)
+				}
+			case "batch":
+				tr, err := mlp.NewTrainer(batchSize, trainingWorkers, learningRate, shuffleSeed)
+				if err != nil {
+					fmt.Printf("couldn't instantiate a trainer: %v\n", err)
+					os.Exit(-1)
+				}
+				tr.Monitor, tr.Metrics = monitor, trainingMetrics
+
+				if _, err := tr.Fit(m, xorDataTrain, xorTargetsTrain, trainingPasses, &rec); err != nil {
+					fmt.Printf("couldn't train the MLP: %v\n", err)
+					os.Exit(-1)
+				}
+			case "async":
+				// Each pass over the data counts as a single step.
+				for i := 1; i <= trainingPasses; i++ {
+					m.AdaptAsync(xorDataTrain, xorTargetsTrain, learningRate, trainingWorkers)
+					checkStep()
+					if trainingMetrics != nil {
+						trainingMetrics.ObserveSteps(1, trainDataThreshold)
+					}
+					record(i, i, i*trainDataThreshold, true)
+				}
 			}
 			fmt.Printf("done!\n")
 
+			if historyPath != "" {
+				if err := writeHistory(&rec.History, historyPath); err != nil {
+					fmt.Printf("couldn't write the training history: %v\n", err)
+					os.Exit(-1)
+				}
+			}
+
+			m.Training = false
+
+			fmt.Printf("\nTraining loss: %2.5f\n", m.Loss(xorDataTrain, toTargets(xorLabelsTrain)))
+
 			tr := "+ ------------------------------------------- +"
 
 			fmt.Printf("\nTESTING RESULTS:\n\t%s\n", tr)
@@ -104,6 +245,31 @@ var (
 
 			fmt.Printf("\t%s\n\t|       TESTING ERROR RATE -> %2.5f         |\n\t%s\n",
 				tr, mlp.ErrorRate(outputPredTest, xorLabelsTest), tr)
+
+			if plotPath != "" {
+				if err := plot.PlotTraining(&rec.History, m, xorDataTest, xorLabelsTest, plotPath); err != nil {
+					fmt.Printf("couldn't plot the training: %v\n", err)
+					os.Exit(-1)
+				}
+				fmt.Printf("\nPlotted the training to %s\n", plotPath)
+			}
+
+			if modelPath != "" {
+				if err := m.Save(modelPath); err != nil {
+					fmt.Printf("couldn't save the MLP: %v\n", err)
+					os.Exit(-1)
+				}
+				fmt.Printf("\nSaved the MLP to %s\n", modelPath)
+			}
 		},
 	}
 )
+
+// toTargets wraps each label into a single-dimensional target.
+func toTargets(labels []float64) [][]float64 {
+	targets := make([][]float64, len(labels))
+	for i, l := range labels {
+		targets[i] = []float64{l}
+	}
+	return targets
+}
@@ -1,18 +1,125 @@
 package mlp
 
-import "math"
+import (
+	"fmt"
+	"math"
 
+	"gonum.org/v1/gonum/mat"
+)
+
+// Activation bundles an activation function together with its derivative,
+// which is what back-propagation needs. The derivative gets both the net
+// activation and the activation itself so that it can use the cheapest one.
+type Activation struct {
+	Name  string
+	F     func(float64) float64
+	Deriv func(net, act float64) float64
+}
+
+var (
+	SigmoidAct  = Activation{Name: "sigmoid", F: Sigmoid, Deriv: SigmoidDeriv}
+	ReLuAct     = Activation{Name: "relu", F: ReLu, Deriv: ReLuDeriv}
+	UnitStepAct = Activation{Name: "unitstep", F: UnitStep, Deriv: UnitStepDeriv}
+
+	// Activations maps the lowercase name of each built-in activation to it.
+	Activations map[string]Activation = map[string]Activation{
+		SigmoidAct.Name:  SigmoidAct,
+		ReLuAct.Name:     ReLuAct,
+		UnitStepAct.Name: UnitStepAct,
+	}
+)
+
+// Sigmoid only ever exponentiates non-positive numbers so that it can't
+// overflow, however large the input.
 func Sigmoid(x float64) float64 {
-	return 1 / (1 + math.Pow(math.E, -x))
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
+
+// LogSigmoid computes log(Sigmoid(x)) without ever taking the logarithm of 0,
+// which is what Sigmoid underflows to for large negative inputs.
+func LogSigmoid(x float64) float64 {
+	if x >= 0 {
+		return -math.Log1p(math.Exp(-x))
+	}
+	return x - math.Log1p(math.Exp(x))
+}
+
+func SigmoidDeriv(x, y float64) float64 {
+	return y * (1 - y)
 }
 
 func ReLu(x float64) float64 {
 	return math.Max(0, x)
 }
 
+func ReLuDeriv(x, y float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
 func UnitStep(x float64) float64 {
 	if x > 0 {
 		return 1
 	}
 	return 0
 }
+
+// UnitStepDeriv is zero everywhere it's defined: MLPs using the unit step won't
+// learn through back-propagation.
+func UnitStepDeriv(x, y float64) float64 {
+	return 0
+}
+
+// ActivationLayer applies an activation function to each of its inputs.
+type ActivationLayer struct {
+	Act Activation
+
+	x, y, dx *mat.Dense
+}
+
+func NewActivationLayer(act Activation) *ActivationLayer {
+	return &ActivationLayer{Act: act}
+}
+
+func (a *ActivationLayer) Forward(x *mat.Dense, training bool) *mat.Dense {
+	r, c := x.Dims()
+	a.x, a.y = x, reuse(a.y, r, c)
+
+	for i := 0; i < r; i++ {
+		y := a.y.RawRowView(i)
+		for j, v := range x.RawRowView(i) {
+			y[j] = a.Act.F(v)
+		}
+	}
+	return a.y
+}
+
+func (a *ActivationLayer) Backward(dy *mat.Dense) *mat.Dense {
+	r, c := dy.Dims()
+	a.dx = reuse(a.dx, r, c)
+
+	for i := 0; i < r; i++ {
+		x, y, dx := a.x.RawRowView(i), a.y.RawRowView(i), a.dx.RawRowView(i)
+		for j, g := range dy.RawRowView(i) {
+			dx[j] = g * a.Act.Deriv(x[j], y[j])
+		}
+	}
+	return a.dx
+}
+
+func (a *ActivationLayer) Params() []*mat.Dense { return nil }
+func (a *ActivationLayer) Grads() []*mat.Dense  { return nil }
+
+func (a *ActivationLayer) Clone(deep bool) Layer {
+	return &ActivationLayer{Act: a.Act}
+}
+
+func (a *ActivationLayer) String() string {
+	return fmt.Sprintf("Activation(%s)", a.Act.Name)
+}
@@ -0,0 +1,42 @@
+package mlp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSigmoidExtremes(t *testing.T) {
+	tests := []struct {
+		x, sigmoid, logSigmoid float64
+	}{
+		{0, 0.5, -math.Ln2},
+		{-1000, 0, -1000},
+		{1000, 1, 0},
+		{-40, 4.248354255291589e-18, -40},
+		{40, 1, -4.248354255291589e-18},
+		{math.Inf(-1), 0, math.Inf(-1)},
+		{math.Inf(1), 1, 0},
+	}
+
+	for _, test := range tests {
+		if got := Sigmoid(test.x); got != test.sigmoid && !floatsEqual(got, test.sigmoid) {
+			t.Errorf("Sigmoid(%g) = %g, expected %g", test.x, got, test.sigmoid)
+		}
+		if got := LogSigmoid(test.x); got != test.logSigmoid && !floatsEqual(got, test.logSigmoid) {
+			t.Errorf("LogSigmoid(%g) = %g, expected %g", test.x, got, test.logSigmoid)
+		}
+	}
+
+	// Both halves of Sigmoid should agree around 0
+	for _, x := range []float64{-1e-3, -1e-12, 1e-12, 1e-3} {
+		if got, want := Sigmoid(x)+Sigmoid(-x), 1.0; !floatsEqual(got, want) {
+			t.Errorf("Sigmoid(%g) + Sigmoid(%g) = %g", x, -x, got)
+		}
+	}
+}
+
+func BenchmarkSigmoid(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Sigmoid(float64(i%200) - 100)
+	}
+}
@@ -0,0 +1,40 @@
+package mlp
+
+import "gonum.org/v1/gonum/mat"
+
+// AdaptAsync goes once through every input applying Adapt's per-sample update
+// from several goroutines at once, Hogwild! style. Each worker takes care of a
+// disjoint chunk of the samples, but they all read and write the shared
+// weights without any locking.
+//
+// Training this way is nondeterministic: updates from different workers
+// interleave arbitrarily and can even overwrite each other, so two runs on the
+// same data and initial weights won't produce the same model. Bear in mind the
+// race detector will flag these concurrent accesses too. In exchange, workers
+// never wait for each other, which tends to pay off when the gradients are
+// sparse and collisions rare.
+func (mlp *Mlp) AdaptAsync(inputs, targets [][]float64, learning_rate float64, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Every worker runs the passes through its own copy of the model sharing
+	// the weights with the rest.
+	seq := mlp.model()
+	replicas := make([]*Sequential, workers)
+	for w := range replicas {
+		replicas[w] = seq.Clone(false).(*Sequential)
+		for _, l := range replicas[w].Layers {
+			if d, ok := l.(*Dropout); ok {
+				d.Seed(mlp.rng.Int63())
+			}
+		}
+	}
+
+	parallelFor(len(inputs), workers, func(worker, from, to int) {
+		x, grad := mat.NewDense(1, mlp.InDim, nil), mat.NewDense(1, mlp.OutDim, nil)
+		for s := from; s < to; s++ {
+			mlp.adaptSample(replicas[worker], x, grad, inputs[s], targets[s], learning_rate)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package mlp
+
+import "testing"
+
+func TestAdaptAsyncConverges(t *testing.T) {
+	if raceEnabled {
+		t.Skip("asynchronous training races on the weights by design")
+	}
+
+	xorData, _, targets := xorTargets(400)
+
+	m, err := NewMlpWith([]int{2, 8, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	for e := 0; e < 300; e++ {
+		m.AdaptAsync(xorData, targets, 0.5, 4)
+	}
+
+	testData, testLabels := GenXor(100, 0.1)
+	var predictions []float64
+	for _, dp := range testData {
+		output, _, _ := m.ComputeActivation(dp)
+		if output[0] > 0.5 {
+			predictions = append(predictions, 1)
+		} else {
+			predictions = append(predictions, 0)
+		}
+	}
+
+	if errRate := ErrorRate(predictions, testLabels); errRate > 0.1 {
+		t.Errorf("asynchronous training didn't converge: error rate %2.5f", errRate)
+	}
+}
@@ -0,0 +1,155 @@
+package mlp
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// AdaptBatch applies a single gradient descent step using the gradient averaged
+// over every input in the mini-batch.
+func (mlp *Mlp) AdaptBatch(inputs, targets [][]float64, learning_rate float64) {
+	mlp.adaptBatch(inputs, targets, learning_rate, 1)
+}
+
+// adaptBatch splits the work of AdaptBatch across the given number of workers.
+func (mlp *Mlp) adaptBatch(inputs, targets [][]float64, learning_rate float64, workers int) {
+	if len(inputs) == 0 {
+		return
+	}
+
+	mlp.batchGradients(inputs, targets, workers)
+	mlp.update(mlp.model(), learning_rate)
+}
+
+// batchGradients fills the gradients of the model with those averaged over
+// the mini-batch using the given number of workers. The result doesn't depend
+// on the number of workers.
+func (mlp *Mlp) batchGradients(inputs, targets [][]float64, workers int) {
+	seq := mlp.model()
+	seq.SetWorkers(workers)
+	defer seq.SetWorkers(1)
+
+	out := mlp.forwardBatch(seq, inputs, mlp.Training)
+
+	mlp.batchGrad = reuse(mlp.batchGrad, len(inputs), mlp.OutDim)
+	scale := 1 / float64(len(inputs))
+	for s, target := range targets {
+		lossGrad(mlp.batchGrad.RawRowView(s), out.RawRowView(s), target, scale)
+	}
+
+	seq.Backward(mlp.batchGrad)
+}
+
+// Trainer drives mini-batch training. The work on each mini-batch is split
+// across Workers goroutines computing the gradients in parallel on the shared
+// weights, which are then applied in a single update. The result is
+// the same regardless of the number of workers. A Trainer built without
+// NewTrainer shuffles the data as if seeded with 1.
+type Trainer struct {
+	BatchSize    int
+	Workers      int
+	LearningRate float64
+
+	// Monitor checks the health of the MLP after every step when set.
+	Monitor *HealthMonitor
+
+	// Metrics tracks every step and, within Fit, every record when set.
+	Metrics *TrainingMetrics
+
+	rng *rand.Rand
+}
+
+// NewTrainer returns a Trainer shuffling the data with the given seed.
+func NewTrainer(batchSize, workers int, learning_rate float64, seed int64) (*Trainer, error) {
+	if batchSize < 1 {
+		return nil, fmt.Errorf("the batch size should be at least 1")
+	}
+	if workers < 1 {
+		return nil, fmt.Errorf("we need at least 1 worker")
+	}
+	return &Trainer{BatchSize: batchSize, Workers: workers, LearningRate: learning_rate, rng: rand.New(rand.NewSource(seed))}, nil
+}
+
+// Step applies a single update for the given mini-batch.
+func (t *Trainer) Step(mlp *Mlp, inputs, targets [][]float64) error {
+	mlp.adaptBatch(inputs, targets, t.LearningRate, t.Workers)
+	if t.Metrics != nil {
+		t.Metrics.ObserveStep(mlp, len(inputs))
+	}
+	if t.Monitor != nil {
+		return t.Monitor.Check(mlp)
+	}
+	return nil
+}
+
+// Epoch shuffles the data and goes through it once in mini-batches.
+func (t *Trainer) Epoch(mlp *Mlp, inputs, targets [][]float64) error {
+	return t.epoch(mlp, inputs, targets, nil)
+}
+
+// epoch runs Epoch, calling afterStep with the size of each mini-batch once
+// it's been trained on.
+func (t *Trainer) epoch(mlp *Mlp, inputs, targets [][]float64, afterStep func(n int)) error {
+	if len(inputs) != len(targets) {
+		return fmt.Errorf("got %d inputs but %d targets", len(inputs), len(targets))
+	}
+
+	if t.BatchSize < 1 {
+		return fmt.Errorf("the batch size should be at least 1")
+	}
+	if t.rng == nil {
+		t.rng = rand.New(rand.NewSource(1))
+	}
+
+	perm := t.rng.Perm(len(inputs))
+	batchIn, batchTgt := make([][]float64, 0, t.BatchSize), make([][]float64, 0, t.BatchSize)
+	for from := 0; from < len(perm); from += t.BatchSize {
+		to := from + t.BatchSize
+		if to > len(perm) {
+			to = len(perm)
+		}
+
+		batchIn, batchTgt = batchIn[:0], batchTgt[:0]
+		for _, i := range perm[from:to] {
+			batchIn, batchTgt = append(batchIn, inputs[i]), append(batchTgt, targets[i])
+		}
+		if err := t.Step(mlp, batchIn, batchTgt); err != nil {
+			return err
+		}
+		if afterStep != nil {
+			afterStep(len(batchIn))
+		}
+	}
+	return nil
+}
+
+// Fit trains the MLP for the given number of epochs, letting rec evaluate it
+// along the way. It returns the resulting history even if training fails.
+func (t *Trainer) Fit(mlp *Mlp, inputs, targets [][]float64, epochs int, rec *Recorder) (*History, error) {
+	rec.Start()
+
+	step, samples := 0, 0
+	for e := 1; e <= epochs; e++ {
+		err := t.epoch(mlp, inputs, targets, func(n int) {
+			step, samples = step+1, samples+n
+			if rec.Every > 0 && step%rec.Every == 0 {
+				t.record(rec, mlp, inputs, targets, e, step, samples)
+			}
+		})
+		if err != nil {
+			return &rec.History, err
+		}
+		if rec.Every == 0 {
+			t.record(rec, mlp, inputs, targets, e, step, samples)
+		}
+	}
+
+	return &rec.History, nil
+}
+
+func (t *Trainer) record(rec *Recorder, mlp *Mlp, inputs, targets [][]float64, epoch, step, samples int) {
+	r := rec.Record(mlp, inputs, targets, epoch, step, samples)
+	if t.Metrics != nil {
+		t.Metrics.ObserveRecord(r)
+	}
+}
@@ -0,0 +1,135 @@
+package mlp
+
+import (
+	"fmt"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestAdaptBatchSingleSample(t *testing.T) {
+	online, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	batch, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	// Each MLP gets its own weights: were they shared, both updates would
+	// land on the same matrices and the comparison below would always pass
+	online.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+	batch.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+	for i := range online.Weights {
+		if sameStorage(online.Weights[i], batch.Weights[i].RawMatrix().Data) {
+			t.Fatalf("both MLPs share weight matrix %d", i)
+		}
+	}
+
+	online.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+	batch.AdaptBatch([][]float64{{1, 0}}, [][]float64{{1}}, 0.5)
+
+	for i := range online.Weights {
+		if !mat.EqualApprox(online.Weights[i], batch.Weights[i], 1e-12) {
+			t.Errorf("weight matrix %d mismatch: %6.3f != %6.3f", i,
+				mat.Formatted(batch.Weights[i], mat.FormatMATLAB()), mat.Formatted(online.Weights[i], mat.FormatMATLAB()))
+		}
+	}
+}
+
+func TestParallelTrainerMatchesSerial(t *testing.T) {
+	xorData, _, targets := xorTargets(100)
+
+	ref, err := NewMlpWith([]int{2, 5, 3, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	train := func(workers int) *Mlp {
+		m, err := NewMlpWith([]int{2, 5, 3, 1}, SigmoidAct, 1)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		for i, w := range ref.Weights {
+			m.Weights[i].Copy(w)
+		}
+
+		tr, err := NewTrainer(16, workers, 0.5, 42)
+		if err != nil {
+			t.Fatalf("NewTrainer() returned an error: %v", err)
+		}
+		for e := 0; e < 20; e++ {
+			if err := tr.Epoch(m, xorData, targets); err != nil {
+				t.Fatalf("Epoch() returned an error: %v", err)
+			}
+		}
+		return m
+	}
+
+	serial := train(1)
+	for _, workers := range []int{2, 3, 8, 32} {
+		parallel := train(workers)
+		for i := range serial.Weights {
+			if !mat.Equal(serial.Weights[i], parallel.Weights[i]) {
+				t.Errorf("weight matrix %d mismatch with %d workers: %6.3f != %6.3f", i, workers,
+					mat.Formatted(parallel.Weights[i], mat.FormatMATLAB()), mat.Formatted(serial.Weights[i], mat.FormatMATLAB()))
+			}
+		}
+	}
+}
+
+func TestTrainerLiteral(t *testing.T) {
+	xorData, _, targets := xorTargets(20)
+
+	newXorMlp := func() *Mlp {
+		m, err := NewMlpWith([]int{2, 3, 1}, SigmoidAct, 1)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		return m
+	}
+	m := newXorMlp()
+	if err := (&Trainer{}).Epoch(m, xorData, targets); err == nil {
+		t.Errorf("Epoch() accepted a batch size of 0")
+	}
+
+	seeded, err := NewTrainer(4, 1, 0.5, 1)
+	if err != nil {
+		t.Fatalf("NewTrainer() returned an error: %v", err)
+	}
+	a, b := m, newXorMlp()
+	if err := (&Trainer{BatchSize: 4, LearningRate: 0.5}).Epoch(a, xorData, targets); err != nil {
+		t.Fatalf("Epoch() returned an error: %v", err)
+	}
+	if err := seeded.Epoch(b, xorData, targets); err != nil {
+		t.Fatalf("Epoch() returned an error: %v", err)
+	}
+	for i := range a.Weights {
+		if !mat.Equal(a.Weights[i], b.Weights[i]) {
+			t.Errorf("weight matrix %d differs from that of a Trainer seeded with 1", i)
+		}
+	}
+}
+
+func BenchmarkTrainerEpoch(b *testing.B) {
+	xorData, _, targets := xorTargets(1024)
+
+	for _, workers := range []int{1, 2, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			m, err := NewMlpWith([]int{2, 64, 64, 1}, SigmoidAct, 1)
+			if err != nil {
+				b.Fatalf("NewMlpWith() returned an error: %v", err)
+			}
+			tr, err := NewTrainer(128, workers, 0.05, 42)
+			if err != nil {
+				b.Fatalf("NewTrainer() returned an error: %v", err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tr.Epoch(m, xorData, targets)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package mlp
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GradClip bounds the gradients before they update the parameters, keeping a
+// single bad step from blowing the weights up. When Value is positive, each
+// gradient entry is clamped to [-Value, Value]. When Norm is positive, every
+// gradient is then scaled down so that the L2 norm over all of them together
+// is at most Norm. The regularization penalties aren't clipped.
+type GradClip struct {
+	Value float64
+	Norm  float64
+}
+
+// Apply clips the given gradients in place, returning their global L2 norm
+// before scaling them down.
+func (c *GradClip) Apply(grads []*mat.Dense) float64 {
+	c.clipValues(grads)
+
+	norm := math.Sqrt(sumSquares(grads))
+	c.scale(grads, norm)
+	return norm
+}
+
+func (c *GradClip) clipValues(grads []*mat.Dense) {
+	if c.Value <= 0 {
+		return
+	}
+	for _, g := range grads {
+		data := g.RawMatrix().Data
+		for j, v := range data {
+			data[j] = math.Max(-c.Value, math.Min(c.Value, v))
+		}
+	}
+}
+
+// scale scales the gradients down if their global norm exceeds Norm.
+func (c *GradClip) scale(grads []*mat.Dense, norm float64) {
+	if c.Norm <= 0 || norm <= c.Norm {
+		return
+	}
+	for _, g := range grads {
+		data := g.RawMatrix().Data
+		for j := range data {
+			data[j] *= c.Norm / norm
+		}
+	}
+}
+
+func sumSquares(grads []*mat.Dense) float64 {
+	sum := 0.0
+	for _, g := range grads {
+		for _, v := range g.RawMatrix().Data {
+			sum += v * v
+		}
+	}
+	return sum
+}
+
+// clip applies the MLP's gradient clipping to the gradients of every layer in
+// seq.
+func (mlp *Mlp) clip(seq *Sequential) {
+	c := &mlp.GradClip
+	if c.Value <= 0 && c.Norm <= 0 {
+		return
+	}
+
+	for _, l := range seq.Layers {
+		c.clipValues(l.Grads())
+	}
+	if c.Norm <= 0 {
+		return
+	}
+
+	norm := 0.0
+	for _, l := range seq.Layers {
+		norm += sumSquares(l.Grads())
+	}
+	norm = math.Sqrt(norm)
+
+	for _, l := range seq.Layers {
+		c.scale(l.Grads(), norm)
+	}
+}
+
+// GradNorm returns the global L2 norm of the gradients applied by the last
+// training step, after clipping them.
+func (mlp *Mlp) GradNorm() float64 {
+	return math.Sqrt(sumSquares(mlp.model().Grads()))
+}
@@ -0,0 +1,77 @@
+package mlp
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGradClipApply(t *testing.T) {
+	grads := func() []*mat.Dense {
+		return []*mat.Dense{mat.NewDense(1, 2, []float64{3, -10}), mat.NewDense(1, 1, []float64{0.5})}
+	}
+
+	tests := []struct {
+		name string
+		clip GradClip
+		norm float64
+		want []float64
+	}{
+		{"none", GradClip{}, math.Sqrt(109.25), []float64{3, -10, 0.5}},
+		{"value", GradClip{Value: 2}, math.Sqrt(8.25), []float64{2, -2, 0.5}},
+		{"norm", GradClip{Norm: 1}, math.Sqrt(109.25), []float64{3 / math.Sqrt(109.25), -10 / math.Sqrt(109.25), 0.5 / math.Sqrt(109.25)}},
+		{"both", GradClip{Value: 4, Norm: 2.5}, math.Sqrt(25.25), []float64{3 * 2.5 / math.Sqrt(25.25), -4 * 2.5 / math.Sqrt(25.25), 0.5 * 2.5 / math.Sqrt(25.25)}},
+		{"loose", GradClip{Value: 20, Norm: 20}, math.Sqrt(109.25), []float64{3, -10, 0.5}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := grads()
+			if norm := test.clip.Apply(g); !floatsEqual(norm, test.norm) {
+				t.Errorf("wrong norm: %g != %g", norm, test.norm)
+			}
+			got := append(append([]float64(nil), g[0].RawMatrix().Data...), g[1].RawMatrix().Data...)
+			for i := range got {
+				if !floatsEqual(got[i], test.want[i]) {
+					t.Errorf("wrong gradients: %v != %v", got, test.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAdaptGradClip(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 8, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	m.GradClip = GradClip{Value: 0.5, Norm: 1}
+
+	before := make([]*mat.Dense, len(m.Weights))
+	for i, w := range m.Weights {
+		before[i] = mat.DenseCopyOf(w)
+	}
+
+	// A huge target makes for a huge gradient
+	lr := 0.1
+	m.Adapt([]float64{3, -2}, []float64{1e6}, lr)
+
+	step := 0.0
+	for i, w := range m.Weights {
+		diff := new(mat.Dense)
+		diff.Sub(w, before[i])
+		step += math.Pow(mat.Norm(diff, 2), 2)
+	}
+	if step = math.Sqrt(step); step > lr+1e-12 {
+		t.Errorf("the update is larger than the clipped gradient allows: %g > %g", step, lr)
+	}
+	if step == 0 {
+		t.Errorf("the weights didn't change at all")
+	}
+
+	if allocs := testing.AllocsPerRun(100, func() { m.Adapt([]float64{3, -2}, []float64{1}, lr) }); allocs != 0 {
+		t.Errorf("Adapt() allocated %.1f times per run with clipping", allocs)
+	}
+}
@@ -0,0 +1,159 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Dense is a fully connected layer. Each row of W holds the weights feeding an
+// output neuron followed by its bias, just like the weight matrices of an Mlp.
+type Dense struct {
+	W *mat.Dense
+
+	grad    *mat.Dense
+	x       *mat.Dense
+	y, dx   *mat.Dense
+	workers int
+
+	params, grads []*mat.Dense
+}
+
+// NewDense returns a layer mapping in inputs to out outputs with its weights
+// and biases drawn from a normal distribution with the given variance.
+func NewDense(in, out int, variance float64, rng *rand.Rand) *Dense {
+	stdDev := math.Sqrt(variance)
+	weights := make([]float64, out*(in+1))
+	for j := range weights {
+		weights[j] = rng.NormFloat64() * stdDev
+	}
+	return &Dense{W: mat.NewDense(out, in+1, weights)}
+}
+
+// Dims returns the number of inputs and outputs of the layer.
+func (d *Dense) Dims() (in, out int) {
+	r, c := d.W.Dims()
+	return c - 1, r
+}
+
+func (d *Dense) SetWorkers(workers int) {
+	d.workers = workers
+}
+
+func (d *Dense) Forward(x *mat.Dense, training bool) *mat.Dense {
+	m, c := x.Dims()
+	if in, _ := d.Dims(); c != in {
+		panic(mat.ErrShape)
+	}
+	_, out := d.Dims()
+
+	d.x, d.y = x, reuse(d.y, m, out)
+	if d.workers <= 1 {
+		d.forward(0, m)
+	} else {
+		parallelFor(m, d.workers, func(_, from, to int) { d.forward(from, to) })
+	}
+	return d.y
+}
+
+// forward computes the output for samples [from, to).
+func (d *Dense) forward(from, to int) {
+	rows, cols := d.W.Dims()
+	for s := from; s < to; s++ {
+		x, y := d.x.RawRowView(s), d.y.RawRowView(s)
+		for r := 0; r < rows; r++ {
+			w := d.W.RawRowView(r)
+			acc := 0.0
+			for k, v := range x {
+				acc += w[k] * v
+			}
+			y[r] = acc + w[cols-1]
+		}
+	}
+}
+
+// Backward sums the gradient over the samples in the mini-batch. Each entry
+// is always summed over the samples in the same order so that the result
+// doesn't depend on the number of workers.
+func (d *Dense) Backward(dy *mat.Dense) *mat.Dense {
+	m, _ := dy.Dims()
+	rows, cols := d.W.Dims()
+
+	d.grad, d.dx = reuse(d.grad, rows, cols), reuse(d.dx, m, cols-1)
+	if d.workers <= 1 {
+		d.backwardInput(dy, 0, m)
+		d.backwardWeights(dy, 0, rows)
+	} else {
+		parallelFor(m, d.workers, func(_, from, to int) { d.backwardInput(dy, from, to) })
+		parallelFor(rows, d.workers, func(_, from, to int) { d.backwardWeights(dy, from, to) })
+	}
+	return d.dx
+}
+
+// backwardInput computes the derivative with respect to the input of samples
+// [from, to). The biases don't propagate back.
+func (d *Dense) backwardInput(dy *mat.Dense, from, to int) {
+	for s := from; s < to; s++ {
+		dx := d.dx.RawRowView(s)
+		for k := range dx {
+			dx[k] = 0
+		}
+		for r, g := range dy.RawRowView(s) {
+			w := d.W.RawRowView(r)
+			for k := range dx {
+				dx[k] += g * w[k]
+			}
+		}
+	}
+}
+
+// backwardWeights computes the gradient of weight rows [from, to).
+func (d *Dense) backwardWeights(dy *mat.Dense, from, to int) {
+	m, _ := dy.Dims()
+	for r := from; r < to; r++ {
+		g := d.grad.RawRowView(r)
+		for j := range g {
+			g[j] = 0
+		}
+		for s := 0; s < m; s++ {
+			delta := dy.At(s, r)
+			for k, v := range d.x.RawRowView(s) {
+				g[k] += delta * v
+			}
+			g[len(g)-1] += delta
+		}
+	}
+}
+
+func (d *Dense) Params() []*mat.Dense {
+	if len(d.params) == 0 || d.params[0] != d.W {
+		d.params = []*mat.Dense{d.W}
+	}
+	return d.params
+}
+
+func (d *Dense) Grads() []*mat.Dense {
+	if d.grad == nil {
+		r, c := d.W.Dims()
+		d.grad = mat.NewDense(r, c, nil)
+	}
+	if len(d.grads) == 0 || d.grads[0] != d.grad {
+		d.grads = []*mat.Dense{d.grad}
+	}
+	return d.grads
+}
+
+func (d *Dense) Clone(deep bool) Layer {
+	c := Dense{W: d.W, workers: d.workers}
+	if deep {
+		c.W = mat.DenseCopyOf(d.W)
+	}
+	return &c
+}
+
+func (d *Dense) String() string {
+	in, out := d.Dims()
+	return fmt.Sprintf("Dense(%d -> %d)", in, out)
+}
@@ -0,0 +1,85 @@
+package mlp
+
+import (
+	"fmt"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Dropout zeroes each of its inputs with probability Rate while training. It
+// implements inverted dropout: surviving inputs are scaled up by 1 / (1 - Rate)
+// so that nothing needs to change at inference, where it does nothing at all.
+type Dropout struct {
+	Rate float64
+
+	rng *rand.Rand
+
+	// mask holds the factors the inputs were scaled by in the last forward
+	// pass when dropped is set.
+	mask, y, dx *mat.Dense
+	dropped     bool
+}
+
+// NewDropout returns a dropout layer drawing its masks from rng.
+func NewDropout(rate float64, rng *rand.Rand) *Dropout {
+	return &Dropout{Rate: rate, rng: rng}
+}
+
+func (d *Dropout) Forward(x *mat.Dense, training bool) *mat.Dense {
+	d.dropped = training && d.Rate > 0
+	if !d.dropped {
+		return x
+	}
+
+	// Masks are drawn sample after sample so that they don't depend on how
+	// the work is split.
+	r, c := x.Dims()
+	d.mask, d.y = reuse(d.mask, r, c), reuse(d.y, r, c)
+	for i := 0; i < r; i++ {
+		mask, y := d.mask.RawRowView(i), d.y.RawRowView(i)
+		for j, v := range x.RawRowView(i) {
+			if d.rng.Float64() < d.Rate {
+				mask[j] = 0
+			} else {
+				mask[j] = 1 / (1 - d.Rate)
+			}
+			y[j] = v * mask[j]
+		}
+	}
+	return d.y
+}
+
+func (d *Dropout) Backward(dy *mat.Dense) *mat.Dense {
+	if !d.dropped {
+		return dy
+	}
+
+	r, c := dy.Dims()
+	d.dx = reuse(d.dx, r, c)
+	for i := 0; i < r; i++ {
+		mask, dx := d.mask.RawRowView(i), d.dx.RawRowView(i)
+		for j, g := range dy.RawRowView(i) {
+			dx[j] = g * mask[j]
+		}
+	}
+	return d.dx
+}
+
+func (d *Dropout) Params() []*mat.Dense { return nil }
+func (d *Dropout) Grads() []*mat.Dense  { return nil }
+
+// Clone gives the copy its own random number generator, seeded from the
+// original one so that clones draw different masks.
+func (d *Dropout) Clone(deep bool) Layer {
+	return &Dropout{Rate: d.Rate, rng: rand.New(rand.NewSource(d.rng.Int63()))}
+}
+
+// Seed reseeds the random number generator masks are drawn from.
+func (d *Dropout) Seed(seed int64) {
+	d.rng.Seed(seed)
+}
+
+func (d *Dropout) String() string {
+	return fmt.Sprintf("Dropout(%g)", d.Rate)
+}
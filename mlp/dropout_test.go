@@ -0,0 +1,114 @@
+package mlp
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDropoutModes(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 50, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	output, _, _ := m.ComputeActivation([]float64{1, 0})
+	want := output[0]
+
+	m.Dropout = []float64{0.5}
+
+	m.Training = false
+	for i := 0; i < 10; i++ {
+		if output, _, _ := m.ComputeActivation([]float64{1, 0}); output[0] != want {
+			t.Fatalf("inference isn't deterministic with dropout: %6.3f != %6.3f", output[0], want)
+		}
+	}
+
+	m.Training = true
+	_, acts, _ := m.ComputeActivation([]float64{1, 0})
+
+	dropped := 0
+	for _, a := range acts[0].RawMatrix().Data {
+		if a == 0 {
+			dropped++
+		}
+	}
+	if dropped == 0 || dropped == 50 {
+		t.Errorf("dropped %d out of 50 hidden neurons with a rate of 0.5", dropped)
+	}
+}
+
+func TestGradCheckDropout(t *testing.T) {
+	m, err := NewMlpWith([]int{3, 6, 5, 2}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	randomiseWeights(m, 1)
+
+	m.Dropout = []float64{0.3, 0.5}
+	m.SetSeed(1)
+
+	for l, relErr := range GradCheck(m, []float64{0.3, -1, 0.5}, []float64{1, 0}, 1e-6) {
+		if max := mat.Max(relErr); max > 1e-5 {
+			t.Errorf("gradient mismatch for weight matrix %d: max relative error %g", l, max)
+		}
+	}
+}
+
+func TestDropoutParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	inputs, targets := make([][]float64, 64), make([][]float64, 64)
+	for i := range inputs {
+		inputs[i] = []float64{rng.NormFloat64(), rng.NormFloat64()}
+		targets[i] = []float64{rng.Float64()}
+	}
+
+	ref, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	train := func(workers int) *Mlp {
+		m, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		for i, w := range ref.Weights {
+			m.Weights[i].Copy(w)
+		}
+		m.Dropout = []float64{0.2, 0.4}
+		m.SetSeed(7)
+
+		tr, err := NewTrainer(8, workers, 0.5, 42)
+		if err != nil {
+			t.Fatalf("NewTrainer() returned an error: %v", err)
+		}
+		for e := 0; e < 5; e++ {
+			if err := tr.Epoch(m, inputs, targets); err != nil {
+				t.Fatalf("Epoch() returned an error: %v", err)
+			}
+		}
+		return m
+	}
+
+	serial, parallel := train(1), train(4)
+	for i := range serial.Weights {
+		if !mat.Equal(serial.Weights[i], parallel.Weights[i]) {
+			t.Errorf("weight matrix %d mismatch: %6.3f != %6.3f", i,
+				mat.Formatted(parallel.Weights[i], mat.FormatMATLAB()), mat.Formatted(serial.Weights[i], mat.FormatMATLAB()))
+		}
+	}
+}
+
+func TestDropoutClone(t *testing.T) {
+	d := NewDropout(0.5, rand.New(rand.NewSource(1)))
+	x := mat.NewDense(1, 100, nil)
+	x.Apply(func(_, _ int, _ float64) float64 { return 1 }, x)
+
+	a, b := d.Clone(false), d.Clone(false)
+	if mat.Equal(a.Forward(x, true), b.Forward(x, true)) {
+		t.Errorf("clones drew the same dropout mask")
+	}
+}
@@ -0,0 +1,19 @@
+package mlp
+
+// floatsEqual checks whether a and b match up to rounding errors.
+func floatsEqual(a, b float64) bool {
+	return relativeError(a, b) < 1e-12
+}
+
+// floatSlicesEqual checks whether a and b hold exactly the same values.
+func floatSlicesEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package mlp
+
+import (
+	"math"
+	"math/rand"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// GradCheck compares the gradients back-propagation computes for a single
+// sample with those obtained through central finite differences of the loss,
+// which includes the regularization penalty. The check runs on a copy of the
+// model, so it leaves the MLP, its random number generator included, untouched.
+//
+// It returns the relative error for every parameter in the order the layers
+// hold them. Each weight matrix is laid out just like itself and, for
+// normalised layers, it's followed by the scale and shift as row vectors.
+func GradCheck(model *Mlp, input, target []float64, eps float64) []*mat.Dense {
+	// Cloning the dropout layers would draw from the random number generator
+	// of the model, so they share a new one instead
+	rng := rand.New(rand.NewSource(1))
+	seq := NewSequential()
+	var weights []*mat.Dense
+	for _, l := range model.model().Layers {
+		switch l := l.(type) {
+		case *Dropout:
+			seq.Layers = append(seq.Layers, NewDropout(l.Rate, rng))
+		case *Dense:
+			d := l.Clone(true).(*Dense)
+			seq.Layers, weights = append(seq.Layers, d), append(weights, d.W)
+		default:
+			seq.Layers = append(seq.Layers, l.Clone(true))
+		}
+	}
+
+	// Replay the same dropout masks on every forward pass
+	x, grad := mat.NewDense(1, model.InDim, nil), mat.NewDense(1, model.OutDim, nil)
+	forward := func() *mat.Dense {
+		rng.Seed(1)
+		return model.forwardSample(seq, x, input)
+	}
+	loss := func() float64 {
+		out := forward()
+		return squaredError(out.RawRowView(0), target) + model.Regularization.penalty(weights)
+	}
+
+	lossGrad(grad.RawRowView(0), forward().RawRowView(0), target, 1)
+	seq.Backward(grad)
+
+	// Grab the analytic gradients before the forward passes below overwrite
+	// them.
+	var params, analytic []*mat.Dense
+	for _, l := range seq.Layers {
+		grads := l.Grads()
+		for k, p := range l.Params() {
+			grad := mat.DenseCopyOf(grads[k])
+			if d, ok := l.(*Dense); ok {
+				model.Regularization.addGrad(grad, d.W)
+			}
+			params, analytic = append(params, p), append(analytic, grad)
+		}
+	}
+
+	var relErrs []*mat.Dense
+	for l, p := range params {
+		r, c := p.Dims()
+		relErr := mat.NewDense(r, c, nil)
+
+		for i := 0; i < r; i++ {
+			for j := 0; j < c; j++ {
+				orig := p.At(i, j)
+
+				p.Set(i, j, orig+eps)
+				lossPlus := loss()
+
+				p.Set(i, j, orig-eps)
+				lossMinus := loss()
+
+				p.Set(i, j, orig)
+
+				relErr.Set(i, j, relativeError(analytic[l].At(i, j), (lossPlus-lossMinus)/(2*eps)))
+			}
+		}
+		relErrs = append(relErrs, relErr)
+	}
+
+	return relErrs
+}
+
+// squaredError is the loss back-propagation minimises: half the squared
+// euclidean distance between the output and the target.
+func squaredError(output, target []float64) float64 {
+	loss := 0.0
+	for i, o := range output {
+		loss += (o - target[i]) * (o - target[i])
+	}
+	return loss / 2
+}
+
+func relativeError(a, b float64) float64 {
+	if a == b {
+		return 0
+	}
+	return math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b))
+}
@@ -0,0 +1,74 @@
+package mlp
+
+import (
+	"fmt"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestGradCheck(t *testing.T) {
+	layouts := [][]int{{2, 3, 1}, {3, 4, 4, 2}, {4, 5, 3, 2, 3}}
+
+	for name, act := range Activations {
+		for _, dims := range layouts {
+			t.Run(fmt.Sprintf("%s/%v", name, dims), func(t *testing.T) {
+				m, err := NewMlpWith(dims, act, 1)
+				if err != nil {
+					t.Fatalf("NewMlpWith() returned an error: %v", err)
+				}
+
+				rng := randomiseWeights(m, 1)
+
+				input, target := make([]float64, dims[0]), make([]float64, dims[len(dims)-1])
+				for i := range input {
+					input[i] = rng.NormFloat64()
+				}
+				for i := range target {
+					target[i] = rng.Float64()
+				}
+
+				for l, relErr := range GradCheck(m, input, target, 1e-6) {
+					if max := mat.Max(relErr); max > 1e-5 {
+						t.Errorf("gradient mismatch for weight matrix %d: max relative error %g\n%6.3g",
+							l, max, mat.Formatted(relErr, mat.FormatMATLAB()))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestGradCheckLeavesModel(t *testing.T) {
+	newMlp := func() *Mlp {
+		m, err := NewMlpWith([]int{3, 6, 2}, SigmoidAct, 1)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		if err := m.SetNorm(0, BatchNorm); err != nil {
+			t.Fatalf("SetNorm() returned an error: %v", err)
+		}
+		m.Dropout, m.Training = []float64{0.5}, true
+		m.SetSeed(1)
+		return m
+	}
+	checked, untouched := newMlp(), newMlp()
+	GradCheck(checked, []float64{1, -1, 0.5}, []float64{0, 1}, 1e-6)
+
+	for i := range checked.Weights {
+		if !mat.Equal(checked.Weights[i], untouched.Weights[i]) {
+			t.Errorf("GradCheck() changed weight matrix %d", i)
+		}
+	}
+	if !floatSlicesEqual(checked.Norms[0].RunningMean, untouched.Norms[0].RunningMean) {
+		t.Errorf("GradCheck() changed the running statistics")
+	}
+	// Both should draw the same dropout masks from now on
+	for i := 0; i < 5; i++ {
+		got, _, _ := checked.ComputeActivation([]float64{1, 0, 1})
+		want, _, _ := untouched.ComputeActivation([]float64{1, 0, 1})
+		if !floatSlicesEqual(got, want) {
+			t.Fatalf("GradCheck() changed the random number generator: %v != %v", got, want)
+		}
+	}
+}
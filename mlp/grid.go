@@ -0,0 +1,164 @@
+package mlp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// How much tiles are scaled up by and the gap between them, in pixels.
+const (
+	tileScale = 3
+	tileGap   = 2
+)
+
+// tileGrid lays out tiles of rows x cols values in a grid with the given
+// number of columns. Values are drawn in grayscale, from black at lo to white
+// at hi.
+type tileGrid struct {
+	img        *image.Gray
+	rows, cols int
+	gridCols   int
+	lo, hi     float64
+}
+
+func newTileGrid(n, gridCols, rows, cols int, lo, hi float64) *tileGrid {
+	gridRows := (n + gridCols - 1) / gridCols
+	w := gridCols*(cols*tileScale+tileGap) + tileGap
+	h := gridRows*(rows*tileScale+tileGap) + tileGap
+
+	g := tileGrid{img: image.NewGray(image.Rect(0, 0, w, h)), rows: rows, cols: cols, gridCols: gridCols, lo: lo, hi: hi}
+	for i := range g.img.Pix {
+		g.img.Pix[i] = 255
+	}
+	return &g
+}
+
+// draw draws the row-major values as the i-th tile.
+func (g *tileGrid) draw(i int, values []float64) {
+	x0 := tileGap + (i%g.gridCols)*(g.cols*tileScale+tileGap)
+	y0 := tileGap + (i/g.gridCols)*(g.rows*tileScale+tileGap)
+
+	for r := 0; r < g.rows; r++ {
+		for c := 0; c < g.cols; c++ {
+			v := (values[r*g.cols+c] - g.lo) / (g.hi - g.lo)
+			px := color.Gray{uint8(math.Round(255 * math.Max(0, math.Min(1, v))))}
+			for dy := 0; dy < tileScale; dy++ {
+				for dx := 0; dx < tileScale; dx++ {
+					g.img.SetGray(x0+c*tileScale+dx, y0+r*tileScale+dy, px)
+				}
+			}
+		}
+	}
+}
+
+func (g *tileGrid) save(fname string) error {
+	return writePNG(g.img, fname)
+}
+
+// gridSide returns the number of columns of the squarest grid holding n tiles.
+func gridSide(n int) int {
+	return int(math.Ceil(math.Sqrt(float64(n))))
+}
+
+func checkImgDims(mlp *Mlp, imgs Images) error {
+	if px := int(imgs.ImgRows * imgs.ImgCols); mlp.InDim != px {
+		return fmt.Errorf("the MLP takes %d inputs but the images have %d x %d pixels", mlp.InDim, imgs.ImgRows, imgs.ImgCols)
+	}
+	return nil
+}
+
+// DumpWeights renders the weights feeding each neuron of the first layer as an
+// ImgRows x ImgCols PNG tile, all of them making up a grid. Each tile is scaled
+// on its own so that its largest weight in absolute value is either black or
+// white, with 0 in mid gray. Biases are left out.
+func DumpWeights(mlp *Mlp, imgs Images, fname string) error {
+	if err := checkImgDims(mlp, imgs); err != nil {
+		return err
+	}
+
+	w := mlp.Weights[0]
+	n, _ := w.Dims()
+	g := newTileGrid(n, gridSide(n), int(imgs.ImgRows), int(imgs.ImgCols), -1, 1)
+
+	scaled := make([]float64, mlp.InDim)
+	for i := 0; i < n; i++ {
+		weights := w.RawRowView(i)[:mlp.InDim]
+		max := 0.0
+		for _, v := range weights {
+			max = math.Max(max, math.Abs(v))
+		}
+		for k, v := range weights {
+			if max > 0 {
+				v /= max
+			}
+			scaled[k] = v
+		}
+		g.draw(i, scaled)
+	}
+
+	return g.save(fname)
+}
+
+// DumpActivations renders a row for each of the given images, showing the
+// image itself followed by the activations of the first hidden layer arranged
+// in a square and stretched to the size of the image. Activations are scaled
+// so that the smallest one across every image is black and the largest white.
+func DumpActivations(mlp *Mlp, imgs Images, indexes []int, fname string) error {
+	if err := checkImgDims(mlp, imgs); err != nil {
+		return err
+	}
+	if mlp.NHidden == 0 {
+		return fmt.Errorf("the MLP has no hidden layers")
+	}
+	if len(indexes) == 0 {
+		return fmt.Errorf("no images to render")
+	}
+
+	inputs := make([][]float64, len(indexes))
+	for i, idx := range indexes {
+		if idx < 0 || idx >= len(imgs.Images) {
+			return fmt.Errorf("image index %d out of range [0, %d)", idx, len(imgs.Images))
+		}
+		inputs[i] = Flatten(imgs.Images[idx])
+	}
+
+	seq := mlp.model()
+	mlp.forwardBatch(seq, inputs, false)
+	acts := seq.Output(mlp.layers[0].actIdx)
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range inputs {
+		for _, v := range acts.RawRowView(i) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+
+	rows, cols := int(imgs.ImgRows), int(imgs.ImgCols)
+	g := newTileGrid(2*len(inputs), 2, rows, cols, 0, 1)
+
+	side := gridSide(mlp.HiddenDim[0])
+	tile := make([]float64, rows*cols)
+	for i, input := range inputs {
+		g.draw(2*i, input)
+
+		// Stretch the square of activations over the tile, leaving the cells
+		// past the last neuron black.
+		act := acts.RawRowView(i)
+		for r := 0; r < rows; r++ {
+			for c := 0; c < cols; c++ {
+				tile[r*cols+c] = 0
+				if k := (r*side/rows)*side + c*side/cols; k < len(act) {
+					tile[r*cols+c] = (act[k] - lo) / (hi - lo)
+				}
+			}
+		}
+		g.draw(2*i+1, tile)
+	}
+
+	return g.save(fname)
+}
@@ -0,0 +1,97 @@
+package mlp
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func smallImages(n, rows, cols int) Images {
+	imgs := Images{N: uint32(n), ImgRows: uint32(rows), ImgCols: uint32(cols), Images: make([][][]float64, n)}
+	for i := range imgs.Images {
+		imgs.Images[i] = make([][]float64, rows)
+		for r := range imgs.Images[i] {
+			imgs.Images[i][r] = make([]float64, cols)
+			for c := range imgs.Images[i][r] {
+				imgs.Images[i][r][c] = float64((i+r+c)%3) / 2
+			}
+		}
+	}
+	return imgs
+}
+
+func decodePNG(t *testing.T, fpath string) image.Image {
+	f, err := os.Open(fpath)
+	if err != nil {
+		t.Fatalf("couldn't open %s: %v", fpath, err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("%s isn't a valid PNG: %v", fpath, err)
+	}
+	return img
+}
+
+func TestDumpWeights(t *testing.T) {
+	imgs := smallImages(3, 4, 5)
+	m, err := NewMlpWith([]int{20, 5, 2}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	// Make the largest weight of the first neuron a positive one
+	m.Weights[0].Set(0, 7, 100)
+
+	fpath := filepath.Join(t.TempDir(), "weights.png")
+	if err := DumpWeights(m, imgs, fpath); err != nil {
+		t.Fatalf("DumpWeights() returned an error: %v", err)
+	}
+	img := decodePNG(t, fpath)
+
+	// 5 neurons make up a 3 x 2 grid
+	tileW, tileH := 5*tileScale+tileGap, 4*tileScale+tileGap
+	if b := img.Bounds(); b.Dx() != 3*tileW+tileGap || b.Dy() != 2*tileH+tileGap {
+		t.Errorf("wrong grid size: %v", b)
+	}
+
+	// Weight 7 lies in row 1, column 2 of the tile
+	x, y := tileGap+2*tileScale, tileGap+1*tileScale
+	if r, _, _, _ := img.At(x, y).RGBA(); r>>8 != 255 {
+		t.Errorf("the largest weight isn't white: %d", r>>8)
+	}
+}
+
+func TestDumpActivations(t *testing.T) {
+	imgs := smallImages(3, 4, 4)
+	m, err := NewMlpWith([]int{16, 9, 2}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	fpath := filepath.Join(t.TempDir(), "activations.png")
+	if err := DumpActivations(m, imgs, []int{0, 2}, fpath); err != nil {
+		t.Fatalf("DumpActivations() returned an error: %v", err)
+	}
+	img := decodePNG(t, fpath)
+
+	tile := 4*tileScale + tileGap
+	if b := img.Bounds(); b.Dx() != 2*tile+tileGap || b.Dy() != 2*tile+tileGap {
+		t.Errorf("wrong grid size: %v", b)
+	}
+
+	// The second image is drawn as is at the start of the second row
+	want := uint32(255 * imgs.Images[2][0][0])
+	if r, _, _, _ := img.At(tileGap, tile+tileGap).RGBA(); r>>8 != want {
+		t.Errorf("wrong pixel for the second image: %d != %d", r>>8, want)
+	}
+
+	if err := DumpActivations(m, imgs, []int{3}, fpath); err == nil {
+		t.Errorf("DumpActivations() accepted an out of range index")
+	}
+	if err := DumpActivations(m, smallImages(1, 5, 5), []int{0}, fpath); err == nil {
+		t.Errorf("DumpActivations() accepted images of the wrong size")
+	}
+}
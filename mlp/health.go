@@ -0,0 +1,134 @@
+package mlp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// HealthMonitor checks the activations of the last forward pass, the gradients
+// of the last backward pass and the parameters of a model for NaN and Inf
+// values. Call Check after every training step to catch divergence as soon as
+// it happens instead of when looking at the final outputs.
+type HealthMonitor struct {
+	// DumpPath is the file the state of the model is written to when a check
+	// fails. Nothing is dumped if it's empty.
+	DumpPath string
+
+	steps int
+}
+
+// DivergenceError reports the first NaN or Inf a HealthMonitor came across.
+type DivergenceError struct {
+	// Step counts the checks from 1
+	Step int
+
+	// Layer is the index of the offending layer within the model and What
+	// is one of activations, gradients or parameters.
+	Layer     int
+	LayerName string
+	What      string
+
+	// DumpPath is where the state of the model was dumped, if anywhere.
+	DumpPath string
+}
+
+func (e *DivergenceError) Error() string {
+	msg := fmt.Sprintf("training diverged at step %d: non-finite %s in layer %d (%s)", e.Step, e.What, e.Layer, e.LayerName)
+	if e.DumpPath != "" {
+		msg += fmt.Sprintf("; model state dumped to %s", e.DumpPath)
+	}
+	return msg
+}
+
+// Check inspects the MLP after a training step, returning a *DivergenceError
+// if anything isn't finite.
+func (h *HealthMonitor) Check(mlp *Mlp) error {
+	return h.CheckSequential(mlp.model())
+}
+
+// CheckSequential inspects a Sequential model after a training step, returning
+// a *DivergenceError if anything isn't finite.
+func (h *HealthMonitor) CheckSequential(seq *Sequential) error {
+	h.steps++
+
+	// Go through the layers in the order each quantity is computed so that
+	// we report where the trouble started.
+	layer, what := -1, ""
+	if len(seq.outs) == len(seq.Layers) {
+		for i, out := range seq.outs {
+			if out != nil && !finite(out) {
+				layer, what = i, "activations"
+				break
+			}
+		}
+	}
+	for i := len(seq.Layers) - 1; i >= 0 && layer < 0; i-- {
+		if !allFinite(seq.Layers[i].Grads()) {
+			layer, what = i, "gradients"
+		}
+	}
+	for i := 0; i < len(seq.Layers) && layer < 0; i++ {
+		if !allFinite(seq.Layers[i].Params()) {
+			layer, what = i, "parameters"
+		}
+	}
+	if layer < 0 {
+		return nil
+	}
+
+	err := &DivergenceError{Step: h.steps, Layer: layer, LayerName: describe(seq.Layers[layer]), What: what}
+	if h.DumpPath != "" {
+		if dumpErr := dumpState(h.DumpPath, seq); dumpErr != nil {
+			return fmt.Errorf("%v; couldn't dump the model state: %v", err, dumpErr)
+		}
+		err.DumpPath = h.DumpPath
+	}
+	return err
+}
+
+// Finite reports whether every parameter of the MLP is finite.
+func (mlp *Mlp) Finite() bool {
+	return allFinite(mlp.model().Params())
+}
+
+func finite(m *mat.Dense) bool {
+	r, _ := m.Dims()
+	for i := 0; i < r; i++ {
+		for _, v := range m.RawRowView(i) {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func allFinite(ms []*mat.Dense) bool {
+	for _, m := range ms {
+		if !finite(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// dumpState writes the output, parameters and gradients of every layer in a
+// human-readable form. JSON can't hold NaN and Inf, so it's no good here.
+func dumpState(fpath string, seq *Sequential) error {
+	msg := seq.String() + "\n"
+	for i, l := range seq.Layers {
+		msg += fmt.Sprintf("\nLayer %d: %s\n", i, describe(l))
+		if len(seq.outs) == len(seq.Layers) && seq.outs[i] != nil {
+			msg += fmt.Sprintf("\tOutput      -> %v\n", mat.Formatted(seq.outs[i], mat.FormatMATLAB()))
+		}
+		grads := l.Grads()
+		for k, p := range l.Params() {
+			msg += fmt.Sprintf("\tParameter %d -> %v\n", k, mat.Formatted(p, mat.FormatMATLAB()))
+			msg += fmt.Sprintf("\tGradient %d  -> %v\n", k, mat.Formatted(grads[k], mat.FormatMATLAB()))
+		}
+	}
+	return ioutil.WriteFile(fpath, []byte(msg), 0644)
+}
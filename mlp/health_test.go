@@ -0,0 +1,76 @@
+package mlp
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHealthMonitor(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 3, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	dump := filepath.Join(t.TempDir(), "dump.txt")
+	monitor := HealthMonitor{DumpPath: dump}
+
+	for i := 0; i < 3; i++ {
+		m.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+		if err := monitor.Check(m); err != nil {
+			t.Fatalf("Check() failed for a healthy MLP: %v", err)
+		}
+	}
+
+	m.Weights[1].Set(0, 1, math.NaN())
+	m.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+
+	err = monitor.Check(m)
+	var divErr *DivergenceError
+	if !errors.As(err, &divErr) {
+		t.Fatalf("Check() didn't report the divergence: %v", err)
+	}
+
+	// The NaN first shows up in the output of the second dense layer, which
+	// comes after the first one's activation and dropout.
+	if divErr.Step != 4 || divErr.Layer != 3 || divErr.What != "activations" {
+		t.Errorf("wrong divergence report: %v", divErr)
+	}
+	if !strings.Contains(divErr.Error(), "Dense(3 -> 1)") || divErr.DumpPath != dump {
+		t.Errorf("wrong divergence message: %v", divErr)
+	}
+
+	data, err := ioutil.ReadFile(dump)
+	if err != nil {
+		t.Fatalf("couldn't read the dump: %v", err)
+	}
+	if !strings.Contains(string(data), "NaN") {
+		t.Errorf("the dump doesn't show the NaN:\n%s", data)
+	}
+}
+
+func TestTrainerMonitor(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 4, 1}, ReLuAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	m.Weights[0].Set(0, 2, math.Inf(1))
+
+	tr, err := NewTrainer(2, 1, 0.1, 1)
+	if err != nil {
+		t.Fatalf("NewTrainer() returned an error: %v", err)
+	}
+	tr.Monitor = &HealthMonitor{}
+
+	err = tr.Epoch(m, [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}, [][]float64{{0}, {1}, {1}, {0}})
+	var divErr *DivergenceError
+	if !errors.As(err, &divErr) {
+		t.Fatalf("Epoch() didn't report the divergence: %v", err)
+	}
+	if divErr.Step != 1 || divErr.Layer != 0 {
+		t.Errorf("wrong divergence report: %v", divErr)
+	}
+}
@@ -0,0 +1,67 @@
+package mlp
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// xorTargets generates n noisy XOR samples, returning their labels both as
+// they are and as single-output targets.
+func xorTargets(n int) ([][]float64, []float64, [][]float64) {
+	inputs, labels := GenXor(n, 0.1)
+	targets := make([][]float64, len(labels))
+	for i, l := range labels {
+		targets[i] = []float64{l}
+	}
+	return inputs, labels, targets
+}
+
+// randomDense returns an r x c matrix drawn from a standard normal
+// distribution.
+func randomDense(rng *rand.Rand, r, c int) *mat.Dense {
+	m := mat.NewDense(r, c, nil)
+	raw := m.RawMatrix().Data
+	for i := range raw {
+		raw[i] = rng.NormFloat64()
+	}
+	return m
+}
+
+// randomiseWeights draws the weights of the MLP from a standard normal
+// distribution seeded with the given value, returning the random number
+// generator so that tests can keep drawing from it.
+func randomiseWeights(m *Mlp, seed int64) *rand.Rand {
+	rng := rand.New(rand.NewSource(seed))
+	for _, w := range m.Weights {
+		raw := w.RawMatrix().Data
+		for i := range raw {
+			raw[i] = rng.NormFloat64()
+		}
+	}
+	return rng
+}
+
+// trainedXor fits a 2-5-1 MLP to 80 out of 100 XOR samples for 5 epochs,
+// recording its loss and accuracy on the remaining 20 through rec. It returns
+// the MLP, its history and the samples with their labels.
+func trainedXor(t *testing.T, rec *Recorder) (*Mlp, *History, [][]float64, []float64) {
+	t.Helper()
+	inputs, labels, targets := xorTargets(100)
+
+	m, err := NewMlpWith([]int{2, 5, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	tr, err := NewTrainer(10, 1, 0.5, 1)
+	if err != nil {
+		t.Fatalf("NewTrainer() returned an error: %v", err)
+	}
+	rec.ValInputs, rec.ValTargets, rec.Metrics = inputs[80:], targets[80:], []Metric{AccuracyMetric}
+	history, err := tr.Fit(m, inputs[:80], targets[:80], 5, rec)
+	if err != nil {
+		t.Fatalf("Fit() returned an error: %v", err)
+	}
+	return m, history, inputs, labels
+}
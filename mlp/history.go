@@ -0,0 +1,234 @@
+package mlp
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Metric scores the outputs of an MLP against the targets.
+type Metric struct {
+	Name string
+	F    func(outputs, targets [][]float64) float64
+}
+
+var AccuracyMetric = Metric{Name: "accuracy", F: Accuracy}
+
+// Accuracy returns the fraction of outputs predicting the right class. MLPs
+// with a single output predict class 1 when it's above 0.5 and 0 otherwise.
+// Otherwise, the predicted class is the output with the largest value.
+func Accuracy(outputs, targets [][]float64) float64 {
+	if len(outputs) == 0 {
+		return 0
+	}
+
+	hits := 0
+	for i, output := range outputs {
+		if len(output) == 1 {
+			if (output[0] > 0.5) == (targets[i][0] > 0.5) {
+				hits++
+			}
+			continue
+		}
+		if argmax(output) == argmax(targets[i]) {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(outputs))
+}
+
+// PredictedClass thresholds single outputs at 0.5 and takes the largest one
+// otherwise, just like Accuracy.
+func PredictedClass(output []float64) int {
+	if len(output) == 1 {
+		if output[0] > 0.5 {
+			return 1
+		}
+		return 0
+	}
+	return argmax(output)
+}
+
+func argmax(x []float64) int {
+	best := 0
+	for i, v := range x {
+		if v > x[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// Record captures how training was going at some point. Metrics holds the
+// training loss as loss and the value of each Metric on the training data
+// under its name. Those on the validation data get a val_ prefix.
+type Record struct {
+	Epoch   int
+	Step    int
+	Samples int
+
+	// Seconds since training started and samples processed per second since
+	// the previous record
+	Seconds    float64
+	Throughput float64
+
+	Metrics map[string]float64
+}
+
+func (r Record) String() string {
+	msg := fmt.Sprintf("epoch %d step %d:", r.Epoch, r.Step)
+	for _, name := range metricNames([]Record{r}) {
+		msg += fmt.Sprintf(" %s %.5f", name, r.Metrics[name])
+	}
+	return msg + fmt.Sprintf(" (%.1fs, %.0f samples/s)", r.Seconds, r.Throughput)
+}
+
+// History holds every record taken during training in order.
+type History struct {
+	Records []Record
+}
+
+// WriteCSV writes a header followed by a row for each record. Records lacking
+// a metric leave its column empty.
+func (h *History) WriteCSV(w io.Writer) error {
+	names := metricNames(h.Records)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"epoch", "step", "samples", "seconds", "throughput"}, names...)); err != nil {
+		return err
+	}
+	for _, r := range h.Records {
+		row := []string{
+			strconv.Itoa(r.Epoch), strconv.Itoa(r.Step), strconv.Itoa(r.Samples),
+			strconv.FormatFloat(r.Seconds, 'g', -1, 64), strconv.FormatFloat(r.Throughput, 'g', -1, 64),
+		}
+		for _, name := range names {
+			v, ok := r.Metrics[name]
+			if !ok {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONLines writes each record as a JSON object on its own line.
+func (h *History) WriteJSONLines(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, r := range h.Records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MetricNames returns the name of every metric in the history with the losses
+// first and the rest sorted.
+func (h *History) MetricNames() []string {
+	return metricNames(h.Records)
+}
+
+// metricNames returns the name of every metric in the records with the losses
+// first and the rest sorted.
+func metricNames(records []Record) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range records {
+		for name := range r.Metrics {
+			if !seen[name] && name != "loss" && name != "val_loss" {
+				names = append(names, name)
+			}
+			seen[name] = true
+		}
+	}
+	sort.Strings(names)
+
+	for _, loss := range []string{"val_loss", "loss"} {
+		if seen[loss] {
+			names = append([]string{loss}, names...)
+		}
+	}
+	return names
+}
+
+// Recorder evaluates an MLP as it trains, building up a History. It logs
+// every record to Log when set.
+type Recorder struct {
+	// Validation data, which is optional
+	ValInputs  [][]float64
+	ValTargets [][]float64
+
+	Metrics []Metric
+
+	// Every is the number of steps between records, taking one at the end of
+	// each epoch instead when 0.
+	Every int
+
+	Log     io.Writer
+	History History
+
+	start, last time.Time
+	lastSamples int
+}
+
+// Start resets the clock the records are timed with.
+func (r *Recorder) Start() {
+	r.start, r.last, r.lastSamples = time.Now(), time.Now(), 0
+}
+
+// Record evaluates the MLP on the given training data and the validation one,
+// appends the result to the history and logs it. Samples is the amount of
+// samples processed since training started.
+func (r *Recorder) Record(mlp *Mlp, inputs, targets [][]float64, epoch, step, samples int) Record {
+	if r.start.IsZero() {
+		r.Start()
+	}
+
+	now := time.Now()
+	rec := Record{Epoch: epoch, Step: step, Samples: samples, Seconds: now.Sub(r.start).Seconds(), Metrics: map[string]float64{}}
+	if elapsed := now.Sub(r.last).Seconds(); elapsed > 0 {
+		rec.Throughput = float64(samples-r.lastSamples) / elapsed
+	}
+
+	r.evaluate(mlp, inputs, targets, "", rec.Metrics)
+	if len(r.ValInputs) > 0 {
+		r.evaluate(mlp, r.ValInputs, r.ValTargets, "val_", rec.Metrics)
+	}
+
+	// Leave the evaluation out of the next throughput
+	r.last, r.lastSamples = time.Now(), samples
+
+	r.History.Records = append(r.History.Records, rec)
+	if r.Log != nil {
+		fmt.Fprintln(r.Log, rec)
+	}
+	return rec
+}
+
+func (r *Recorder) evaluate(mlp *Mlp, inputs, targets [][]float64, prefix string, metrics map[string]float64) {
+	if len(inputs) == 0 {
+		return
+	}
+	out := mlp.forwardBatch(mlp.model(), inputs, false)
+	metrics[prefix+"loss"] = mlp.loss(out, targets)
+
+	outputs := make([][]float64, len(inputs))
+	for i := range outputs {
+		outputs[i] = out.RawRowView(i)
+	}
+	for _, m := range r.Metrics {
+		metrics[prefix+m.Name] = m.F(outputs, targets)
+	}
+}
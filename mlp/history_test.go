@@ -0,0 +1,93 @@
+package mlp
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestAccuracy(t *testing.T) {
+	outputs := [][]float64{{0.9}, {0.2}, {0.6}, {0.4}}
+	targets := [][]float64{{1}, {0}, {0}, {0}}
+	if got := Accuracy(outputs, targets); got != 0.75 {
+		t.Errorf("wrong single-output accuracy: %g != 0.75", got)
+	}
+
+	outputs = [][]float64{{0.1, 0.7, 0.2}, {0.5, 0.3, 0.2}}
+	targets = [][]float64{{0, 1, 0}, {0, 0, 1}}
+	if got := Accuracy(outputs, targets); got != 0.5 {
+		t.Errorf("wrong multi-output accuracy: %g != 0.5", got)
+	}
+}
+
+func TestFitHistory(t *testing.T) {
+	var log bytes.Buffer
+	m, history, _, _ := trainedXor(t, &Recorder{Log: &log})
+
+	if len(history.Records) != 5 {
+		t.Fatalf("got %d records for 5 epochs", len(history.Records))
+	}
+	for i, r := range history.Records {
+		if r.Epoch != i+1 || r.Step != 8*(i+1) || r.Samples != 80*(i+1) {
+			t.Errorf("wrong progress in record %d: %+v", i, r)
+		}
+		for _, name := range []string{"loss", "val_loss", "accuracy", "val_accuracy"} {
+			if _, ok := r.Metrics[name]; !ok {
+				t.Errorf("record %d lacks %s", i, name)
+			}
+		}
+	}
+	if got := strings.Count(log.String(), "\n"); got != 5 {
+		t.Errorf("logged %d lines for 5 records:\n%s", got, log.String())
+	}
+
+	// Records every 3 steps
+	xorData, _, targets := xorTargets(100)
+	tr := Trainer{BatchSize: 10, Workers: 1, LearningRate: 0.5}
+	if history, _ = tr.Fit(m, xorData, targets, 2, &Recorder{Every: 3}); len(history.Records) != 6 {
+		t.Errorf("got %d records for 20 steps, expected 6", len(history.Records))
+	}
+}
+
+func TestHistoryOutput(t *testing.T) {
+	history := History{Records: []Record{
+		{Epoch: 1, Step: 10, Samples: 100, Seconds: 0.5, Throughput: 200, Metrics: map[string]float64{"loss": 0.25, "accuracy": 0.5}},
+		{Epoch: 2, Step: 20, Samples: 200, Seconds: 1, Throughput: 200, Metrics: map[string]float64{"loss": 0.125, "val_loss": 0.2}},
+	}}
+
+	var buf bytes.Buffer
+	if err := history.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() returned an error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("couldn't parse the CSV: %v", err)
+	}
+	want := [][]string{
+		{"epoch", "step", "samples", "seconds", "throughput", "loss", "val_loss", "accuracy"},
+		{"1", "10", "100", "0.5", "200", "0.25", "", "0.5"},
+		{"2", "20", "200", "1", "200", "0.125", "0.2", ""},
+	}
+	for i := range want {
+		if strings.Join(rows[i], ",") != strings.Join(want[i], ",") {
+			t.Errorf("CSV row %d mismatch: %v != %v", i, rows[i], want[i])
+		}
+	}
+
+	buf.Reset()
+	if err := history.WriteJSONLines(&buf); err != nil {
+		t.Fatalf("WriteJSONLines() returned an error: %v", err)
+	}
+	dec := json.NewDecoder(&buf)
+	for i, r := range history.Records {
+		var got Record
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("couldn't decode line %d: %v", i, err)
+		}
+		if got.Step != r.Step || got.Metrics["loss"] != r.Metrics["loss"] {
+			t.Errorf("line %d mismatch: %+v != %+v", i, got, r)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package mlp
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/nfnt/resize"
+)
+
+// ReadCustomImg reads a PNG or JPEG file holding a single handwritten digit and
+// prepares it with ImportImage.
+func ReadCustomImg(fpath string, rows, cols int) ([][]float64, error) {
+	fd, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	img, _, err := image.Decode(fd)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode %s: %v", fpath, err)
+	}
+	return ImportImage(img, rows, cols)
+}
+
+// ImportImage turns an image of a single digit into a rows x cols one laid out
+// the way MNIST digits are: the image is converted to grayscale, inverted if
+// its background is light, cropped to the bounding box of the digit, scaled so
+// that it fits in a box 20/28 the size of the output and placed so that its
+// centre of mass lies at the centre. Pixels lie within [0, 1] just like those
+// returned by ReadImgs.
+func ImportImage(img image.Image, rows, cols int) ([][]float64, error) {
+	if rows <= 0 || cols <= 0 {
+		return nil, fmt.Errorf("wrong image size %d x %d", rows, cols)
+	}
+
+	gray := grayscale(img)
+	b := gray.Bounds()
+	if b.Empty() {
+		return nil, fmt.Errorf("the image is empty")
+	}
+
+	// MNIST digits are light over a dark background
+	if borderMean(gray) > 127 {
+		for i, v := range gray.Pix {
+			gray.Pix[i] = 255 - v
+		}
+	}
+
+	box, ok := inkBounds(gray)
+	if !ok {
+		return nil, fmt.Errorf("couldn't find a digit in the image")
+	}
+
+	// Scale the longest side of the digit to 20/28 of the output
+	w, h := float64(box.Dx()), float64(box.Dy())
+	fit := math.Min(float64(rows)*20/28/h, float64(cols)*20/28/w)
+	dw, dh := uint(math.Max(1, math.Round(w*fit))), uint(math.Max(1, math.Round(h*fit)))
+	digit := resize.Resize(dw, dh, gray.SubImage(box), resize.Bilinear)
+
+	// Place the centre of mass of the digit at the centre of the output
+	pixels := make([][]float64, dh)
+	mass, cx, cy := 0.0, 0.0, 0.0
+	for y := range pixels {
+		pixels[y] = make([]float64, dw)
+		for x := range pixels[y] {
+			v := float64(color.GrayModel.Convert(digit.At(digit.Bounds().Min.X+x, digit.Bounds().Min.Y+y)).(color.Gray).Y) / 255
+			pixels[y][x] = v
+			mass, cx, cy = mass+v, cx+v*(float64(x)+0.5), cy+v*(float64(y)+0.5)
+		}
+	}
+	offX := int(math.Round(float64(cols)/2 - cx/mass))
+	offY := int(math.Round(float64(rows)/2 - cy/mass))
+
+	out := make([][]float64, rows)
+	for r := range out {
+		out[r] = make([]float64, cols)
+	}
+	for y, row := range pixels {
+		for x, v := range row {
+			if r, c := y+offY, x+offX; r >= 0 && r < rows && c >= 0 && c < cols {
+				out[r][c] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+func grayscale(img image.Image) *image.Gray {
+	b := img.Bounds()
+	gray := image.NewGray(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			gray.Set(x, y, img.At(x, y))
+		}
+	}
+	return gray
+}
+
+// borderMean returns the average value of the pixels on the edges of the image.
+func borderMean(gray *image.Gray) float64 {
+	b := gray.Bounds()
+	sum, n := 0.0, 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if y == b.Min.Y || y == b.Max.Y-1 || x == b.Min.X || x == b.Max.X-1 {
+				sum += float64(gray.GrayAt(x, y).Y)
+				n++
+			}
+		}
+	}
+	return sum / float64(n)
+}
+
+// inkBounds returns the bounding box of the pixels brighter than a fifth of the
+// brightest one, which is what we take for the digit.
+func inkBounds(gray *image.Gray) (image.Rectangle, bool) {
+	max := uint8(0)
+	for _, v := range gray.Pix {
+		if v > max {
+			max = v
+		}
+	}
+	if max == 0 {
+		return image.Rectangle{}, false
+	}
+
+	b, box := gray.Bounds(), image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if gray.GrayAt(x, y).Y > max/5 {
+				box = box.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return box, true
+}
+
+// Flatten concatenates the rows of an image, such as those ReadCustomImg
+// returns, into an input for an MLP.
+func Flatten(img [][]float64) []float64 {
+	var input []float64
+	for _, row := range img {
+		input = append(input, row...)
+	}
+	return input
+}
+
+// ClassProbabilities turns the output of an MLP into a probability for each
+// class. Outputs within [0, 1], such as those of sigmoid neurons, are scaled
+// so that they add up to 1. Any other output is taken as logits and goes
+// through Softmax.
+func ClassProbabilities(output []float64) []float64 {
+	probs, sum := make([]float64, len(output)), 0.0
+	for _, v := range output {
+		if v < 0 || v > 1 {
+			return Softmax(probs, output)
+		}
+		sum += v
+	}
+	if sum == 0 {
+		return Softmax(probs, output)
+	}
+	for i, v := range output {
+		probs[i] = v / sum
+	}
+	return probs
+}
+
+// PredictClasses returns the class the MLP predicts for each of the images in
+// [from, to), as given by PredictedClass.
+func PredictClasses(mlp *Mlp, imgs Images, from, to int) ([]int, error) {
+	if err := checkImgDims(mlp, imgs); err != nil {
+		return nil, err
+	}
+	if from < 0 || to <= from || to > len(imgs.Images) {
+		return nil, fmt.Errorf("wrong image range [%d, %d) for %d images", from, to, len(imgs.Images))
+	}
+
+	inputs := make([][]float64, to-from)
+	for i := range inputs {
+		inputs[i] = Flatten(imgs.Images[from+i])
+	}
+	outputs, err := mlp.Predictor().PredictBatch(inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]int, len(outputs))
+	for i, out := range outputs {
+		classes[i] = PredictedClass(out)
+	}
+	return classes, nil
+}
@@ -0,0 +1,126 @@
+package mlp
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDigit writes a dark bar off the centre of a light image.
+func writeDigit(t *testing.T, fpath string) {
+	img := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(5, 5, 15, 35), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	f, err := os.Create(fpath)
+	if err != nil {
+		t.Fatalf("couldn't create %s: %v", fpath, err)
+	}
+	defer f.Close()
+	if filepath.Ext(fpath) == ".png" {
+		err = png.Encode(f, img)
+	} else {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: 95})
+	}
+	if err != nil {
+		t.Fatalf("couldn't encode %s: %v", fpath, err)
+	}
+}
+
+func TestReadCustomImg(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"digit.png", "digit.jpg"} {
+		fpath := filepath.Join(dir, name)
+		writeDigit(t, fpath)
+
+		img, err := ReadCustomImg(fpath, 28, 28)
+		if err != nil {
+			t.Fatalf("ReadCustomImg() returned an error for %s: %v", name, err)
+		}
+		if len(img) != 28 || len(img[0]) != 28 {
+			t.Fatalf("wrong size for %s: %d x %d", name, len(img), len(img[0]))
+		}
+
+		// The bar becomes a light one 20 pixels tall with its centre of mass
+		// at the centre of the image.
+		mass, cx, cy, top, bottom := 0.0, 0.0, 0.0, 28, -1
+		for r, row := range img {
+			for c, v := range row {
+				if v < 0 || v > 1 {
+					t.Fatalf("pixel (%d, %d) of %s out of [0, 1]: %g", r, c, name, v)
+				}
+				mass, cx, cy = mass+v, cx+v*(float64(c)+0.5), cy+v*(float64(r)+0.5)
+				if v > 0.5 {
+					if r < top {
+						top = r
+					}
+					bottom = r
+				}
+			}
+		}
+		if bottom-top+1 != 20 {
+			t.Errorf("the digit in %s is %d pixels tall", name, bottom-top+1)
+		}
+		if math.Abs(cx/mass-14) > 1 || math.Abs(cy/mass-14) > 1 {
+			t.Errorf("the centre of mass of %s lies at (%.2f, %.2f)", name, cx/mass, cy/mass)
+		}
+		if img[0][0] > 0.05 || img[27][27] > 0.05 {
+			t.Errorf("the background of %s isn't dark", name)
+		}
+	}
+
+	blank := image.NewGray(image.Rect(0, 0, 10, 10))
+	if _, err := ImportImage(blank, 28, 28); err == nil {
+		t.Errorf("ImportImage() found a digit in a blank image")
+	}
+	if _, err := ReadCustomImg(filepath.Join(dir, "missing.png"), 28, 28); err == nil {
+		t.Errorf("ReadCustomImg() read a missing file")
+	}
+}
+
+func TestClassProbabilities(t *testing.T) {
+	for _, tc := range []struct {
+		output, want []float64
+	}{
+		{[]float64{0.2, 0.6, 0.2}, []float64{0.2, 0.6, 0.2}},
+		{[]float64{0.5, 0.5}, []float64{0.5, 0.5}},
+		{[]float64{0, 0}, []float64{0.5, 0.5}},
+		{[]float64{2, -1}, Softmax(make([]float64, 2), []float64{2, -1})},
+	} {
+		got := ClassProbabilities(tc.output)
+		for i := range got {
+			if math.Abs(got[i]-tc.want[i]) > 1e-12 {
+				t.Errorf("wrong probabilities for %v: %v != %v", tc.output, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPredictClasses(t *testing.T) {
+	imgs := smallImages(6, 4, 4)
+	m, err := NewMlpWith([]int{16, 8, 3}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	classes, err := PredictClasses(m, imgs, 2, 6)
+	if err != nil {
+		t.Fatalf("PredictClasses() returned an error: %v", err)
+	}
+	if len(classes) != 4 {
+		t.Fatalf("got %d classes for 4 images", len(classes))
+	}
+	for i, class := range classes {
+		out, _, _ := m.ComputeActivation(Flatten(imgs.Images[2+i]))
+		if want := PredictedClass(out); class != want {
+			t.Errorf("wrong class for image %d: %d != %d", 2+i, class, want)
+		}
+	}
+}
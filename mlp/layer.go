@@ -0,0 +1,178 @@
+package mlp
+
+import (
+	"fmt"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Layer is a building block of a Sequential model. Layers work on mini-batches
+// holding a sample on each row. They keep whatever they need from the forward
+// pass for the backward one together with their own buffers, so a layer can't
+// take part in several passes at once: use Clone to get one that can.
+type Layer interface {
+	// Forward computes the output of the layer for the mini-batch x. The
+	// output is only valid until the next call.
+	Forward(x *mat.Dense, training bool) *mat.Dense
+
+	// Backward takes the derivative of the loss with respect to the output of
+	// the last forward pass, fills the gradients of the parameters and returns
+	// the derivative with respect to the input, which is only valid until the
+	// next call.
+	Backward(dy *mat.Dense) *mat.Dense
+
+	// Params returns the trainable parameters and Grads their gradients in
+	// the same order. Both share the storage of the layer.
+	Params() []*mat.Dense
+	Grads() []*mat.Dense
+
+	// Clone returns a copy of the layer with its own buffers and gradients.
+	// The copy shares the parameters with the original unless deep is set.
+	Clone(deep bool) Layer
+}
+
+// Sequential chains layers, feeding the output of each of them into the next
+// one. It's a Layer itself, so models can be nested.
+type Sequential struct {
+	Layers []Layer
+
+	// The output of each layer in the last forward pass
+	outs []*mat.Dense
+}
+
+func NewSequential(layers ...Layer) *Sequential {
+	return &Sequential{Layers: layers}
+}
+
+func (s *Sequential) Forward(x *mat.Dense, training bool) *mat.Dense {
+	if len(s.outs) != len(s.Layers) {
+		s.outs = make([]*mat.Dense, len(s.Layers))
+	}
+	for i, l := range s.Layers {
+		x = l.Forward(x, training)
+		s.outs[i] = x
+	}
+	return x
+}
+
+func (s *Sequential) Backward(dy *mat.Dense) *mat.Dense {
+	for i := len(s.Layers) - 1; i >= 0; i-- {
+		dy = s.Layers[i].Backward(dy)
+	}
+	return dy
+}
+
+func (s *Sequential) Params() []*mat.Dense {
+	var params []*mat.Dense
+	for _, l := range s.Layers {
+		params = append(params, l.Params()...)
+	}
+	return params
+}
+
+func (s *Sequential) Grads() []*mat.Dense {
+	var grads []*mat.Dense
+	for _, l := range s.Layers {
+		grads = append(grads, l.Grads()...)
+	}
+	return grads
+}
+
+func (s *Sequential) Clone(deep bool) Layer {
+	c := Sequential{Layers: make([]Layer, len(s.Layers))}
+	for i, l := range s.Layers {
+		c.Layers[i] = l.Clone(deep)
+	}
+	return &c
+}
+
+// String summarises the layers of the model, one per line.
+func (s *Sequential) String() string {
+	n := 0
+	for _, p := range s.Params() {
+		r, c := p.Dims()
+		n += r * c
+	}
+
+	msg := fmt.Sprintf("Sequential: %d parameters", n)
+	for i, l := range s.Layers {
+		msg += fmt.Sprintf("\n%s", indent(fmt.Sprintf("%2d: %s", i, describe(l))))
+	}
+	return msg
+}
+
+// Output returns the output of layer i in the last forward pass.
+func (s *Sequential) Output(i int) *mat.Dense {
+	return s.outs[i]
+}
+
+// SetWorkers lets the layers supporting it split their work across the given
+// number of goroutines. The results don't depend on the number of workers.
+func (s *Sequential) SetWorkers(workers int) {
+	for _, l := range s.Layers {
+		if p, ok := l.(interface{ SetWorkers(int) }); ok {
+			p.SetWorkers(workers)
+		}
+	}
+}
+
+// Update applies a plain gradient descent step to every parameter.
+func (s *Sequential) Update(learning_rate float64) {
+	for _, l := range s.Layers {
+		grads := l.Grads()
+		for i, p := range l.Params() {
+			sgdStep(p, grads[i], learning_rate)
+		}
+	}
+}
+
+func sgdStep(p, grad *mat.Dense, learning_rate float64) {
+	pData, gData := p.RawMatrix().Data, grad.RawMatrix().Data
+	for j, g := range gData {
+		pData[j] -= learning_rate * g
+	}
+}
+
+// reuse returns a matrix of the given shape, reusing m's storage when
+// possible. Its contents are undefined.
+func reuse(m *mat.Dense, r, c int) *mat.Dense {
+	if m == nil {
+		return mat.NewDense(r, c, nil)
+	}
+	if mr, mc := m.Dims(); mr == r && mc == c {
+		return m
+	}
+	if data := m.RawMatrix().Data; cap(data) >= r*c {
+		return mat.NewDense(r, c, data[:r*c])
+	}
+	return mat.NewDense(r, c, nil)
+}
+
+// parallelFor splits [0, n) into contiguous chunks and hands each of them to
+// fn on a different goroutine, waiting for all of them to finish. Workers are
+// numbered from 0 and there are never more than the requested amount.
+func parallelFor(n, workers int, fn func(worker, from, to int)) {
+	if workers <= 1 || n <= 1 {
+		fn(0, 0, n)
+		return
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for worker, from := 0, 0; from < n; worker, from = worker+1, from+chunk {
+		to := from + chunk
+		if to > n {
+			to = n
+		}
+		wg.Add(1)
+		go func(worker, from, to int) {
+			defer wg.Done()
+			fn(worker, from, to)
+		}(worker, from, to)
+	}
+	wg.Wait()
+}
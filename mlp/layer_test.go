@@ -0,0 +1,129 @@
+package mlp
+
+import (
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// scale is a custom layer multiplying its inputs by a learned factor.
+type scale struct {
+	a, grad *mat.Dense
+	x, y    *mat.Dense
+	dx      *mat.Dense
+}
+
+func newScale(a float64) *scale {
+	return &scale{a: mat.NewDense(1, 1, []float64{a}), grad: mat.NewDense(1, 1, nil)}
+}
+
+func (s *scale) Forward(x *mat.Dense, training bool) *mat.Dense {
+	s.x, s.y = x, new(mat.Dense)
+	s.y.Scale(s.a.At(0, 0), x)
+	return s.y
+}
+
+func (s *scale) Backward(dy *mat.Dense) *mat.Dense {
+	g := new(mat.Dense)
+	g.MulElem(dy, s.x)
+	s.grad.Set(0, 0, mat.Sum(g))
+
+	s.dx = new(mat.Dense)
+	s.dx.Scale(s.a.At(0, 0), dy)
+	return s.dx
+}
+
+func (s *scale) Params() []*mat.Dense { return []*mat.Dense{s.a} }
+func (s *scale) Grads() []*mat.Dense  { return []*mat.Dense{s.grad} }
+
+func (s *scale) Clone(deep bool) Layer {
+	c := scale{a: s.a, grad: mat.NewDense(1, 1, nil)}
+	if deep {
+		c.a = mat.DenseCopyOf(s.a)
+	}
+	return &c
+}
+
+func TestSequentialMatchesMlp(t *testing.T) {
+	m, err := NewMlpWith([]int{3, 4, 2}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	seq := NewSequential(
+		&Dense{W: m.Weights[0]}, NewActivationLayer(SigmoidAct),
+		&Dense{W: m.Weights[1]}, NewActivationLayer(SigmoidAct),
+	)
+
+	inputs := [][]float64{{1, 0, -1}, {0.5, 2, 0}}
+	out := seq.Forward(mat.NewDense(2, 3, []float64{1, 0, -1, 0.5, 2, 0}), false)
+	for s, input := range inputs {
+		want, _, _ := m.ComputeActivation(input)
+		if got := out.RawRowView(s); !floatSlicesEqual(got, want) {
+			t.Errorf("output mismatch for sample %d: %v != %v", s, got, want)
+		}
+	}
+}
+
+func TestSequentialCustomLayer(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	custom := newScale(0.1)
+	seq := NewSequential(NewDense(2, 3, 1, rng), NewActivationLayer(ReLuAct), NewDense(3, 1, 1, rng), custom)
+
+	if got := len(seq.Params()); got != 3 {
+		t.Fatalf("got %d parameters, expected 3", got)
+	}
+
+	// Learn y = 2 * (x1 + x2)
+	x, targets := mat.NewDense(4, 2, []float64{0, 1, 1, 0, 1, 1, 0.5, 0.5}), mat.NewDense(4, 1, []float64{2, 2, 4, 2})
+	loss := func() float64 {
+		diff := new(mat.Dense)
+		diff.Sub(seq.Forward(x, true), targets)
+		return mat.Norm(diff, 2)
+	}
+
+	before := loss()
+	for i := 0; i < 200; i++ {
+		dy := new(mat.Dense)
+		dy.Sub(seq.Forward(x, true), targets)
+		dy.Scale(0.25, dy)
+		seq.Backward(dy)
+		seq.Update(0.05)
+	}
+	if after := loss(); after >= before/10 {
+		t.Errorf("training barely improved the loss: %g -> %g", before, after)
+	}
+	if custom.a.At(0, 0) == 0.1 {
+		t.Errorf("the custom layer's parameter didn't change")
+	}
+
+	// Shallow clones share the parameters, deep ones don't
+	shallow, deep := seq.Clone(false), seq.Clone(true)
+	custom.a.Set(0, 0, 42)
+	if got := shallow.Params()[2].At(0, 0); got != 42 {
+		t.Errorf("shallow clone doesn't share the parameters: got %g", got)
+	}
+	if got := deep.Params()[2].At(0, 0); got == 42 {
+		t.Errorf("deep clone shares the parameters")
+	}
+}
+
+func TestDenseWorkers(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	serial := NewDense(5, 7, 1, rng)
+	parallel := serial.Clone(false).(*Dense)
+	parallel.SetWorkers(3)
+
+	x, dy := randomDense(rng, 10, 5), randomDense(rng, 10, 7)
+
+	if !mat.Equal(serial.Forward(x, true), parallel.Forward(x, true)) {
+		t.Errorf("forward pass mismatch")
+	}
+	if !mat.Equal(serial.Backward(dy), parallel.Backward(dy)) {
+		t.Errorf("input derivative mismatch")
+	}
+	if !mat.Equal(serial.Grads()[0], parallel.Grads()[0]) {
+		t.Errorf("weight gradient mismatch")
+	}
+}
@@ -0,0 +1,90 @@
+package mlp
+
+import (
+	"math"
+)
+
+// LogSumExp computes log(sum(exp(x))), subtracting the maximum first so that
+// the exponentials can neither overflow nor all underflow to 0.
+func LogSumExp(x []float64) float64 {
+	if len(x) == 0 {
+		return math.Inf(-1)
+	}
+
+	max := x[0]
+	for _, v := range x[1:] {
+		max = math.Max(max, v)
+	}
+	if math.IsInf(max, 0) {
+		return max
+	}
+
+	sum := 0.0
+	for _, v := range x {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+// Softmax fills dst with the softmax of x and returns it. Both must have the
+// same length, but they can be the same slice.
+func Softmax(dst, x []float64) []float64 {
+	if len(dst) != len(x) {
+		panic("mlp: length mismatch")
+	}
+
+	lse := LogSumExp(x)
+	for i, v := range x {
+		dst[i] = math.Exp(v - lse)
+	}
+	return dst
+}
+
+// LogSoftmax fills dst with the logarithm of the softmax of x and returns it.
+// Unlike taking the logarithm of Softmax, it's finite for every finite input.
+// Both must have the same length, but they can be the same slice.
+func LogSoftmax(dst, x []float64) []float64 {
+	if len(dst) != len(x) {
+		panic("mlp: length mismatch")
+	}
+
+	lse := LogSumExp(x)
+	for i, v := range x {
+		dst[i] = v - lse
+	}
+	return dst
+}
+
+// SoftmaxCrossEntropy computes the cross-entropy between the target
+// distribution and the softmax of logits straight from the latter, which is
+// stable for any finite logits. If grad isn't nil, it's filled with the
+// derivative of the loss with respect to logits, which is softmax(logits) -
+// target for targets adding up to 1.
+func SoftmaxCrossEntropy(logits, target, grad []float64) float64 {
+	if len(logits) != len(target) || (grad != nil && len(grad) != len(logits)) {
+		panic("mlp: length mismatch")
+	}
+
+	lse, sumTarget, loss := LogSumExp(logits), 0.0, 0.0
+	for i, t := range target {
+		if t != 0 {
+			loss -= t * (logits[i] - lse)
+		}
+		sumTarget += t
+	}
+
+	for i := range grad {
+		grad[i] = sumTarget*math.Exp(logits[i]-lse) - target[i]
+	}
+	return loss
+}
+
+// SigmoidCrossEntropy computes the binary cross-entropy between target, which
+// lies within [0, 1], and Sigmoid(logit) straight from the latter, together
+// with its derivative with respect to logit: Sigmoid(logit) - target.
+func SigmoidCrossEntropy(logit, target float64) (loss, grad float64) {
+	// -t * log(s(z)) - (1 - t) * log(1 - s(z)) rearranged so that exp never
+	// overflows.
+	loss = math.Max(logit, 0) - logit*target + math.Log1p(math.Exp(-math.Abs(logit)))
+	return loss, Sigmoid(logit) - target
+}
@@ -0,0 +1,109 @@
+package mlp
+
+import (
+	"math"
+	"testing"
+)
+
+func finiteSlice(x []float64) bool {
+	for _, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSoftmaxExtremes(t *testing.T) {
+	tests := []struct {
+		x, softmax, logSoftmax []float64
+	}{
+		{[]float64{1000, 1000}, []float64{0.5, 0.5}, []float64{-math.Ln2, -math.Ln2}},
+		{[]float64{-1000, -1000}, []float64{0.5, 0.5}, []float64{-math.Ln2, -math.Ln2}},
+		{[]float64{-1000, 0}, []float64{0, 1}, []float64{-1000, 0}},
+		{[]float64{1e308, -1e308}, []float64{1, 0}, []float64{0, math.Inf(-1)}},
+		{[]float64{0, math.Log(3)}, []float64{0.25, 0.75}, []float64{math.Log(0.25), math.Log(0.75)}},
+	}
+
+	for _, test := range tests {
+		softmax, logSoftmax := Softmax(make([]float64, len(test.x)), test.x), LogSoftmax(make([]float64, len(test.x)), test.x)
+		for i := range test.x {
+			if softmax[i] != test.softmax[i] && !floatsEqual(softmax[i], test.softmax[i]) {
+				t.Errorf("Softmax(%v) = %v, expected %v", test.x, softmax, test.softmax)
+				break
+			}
+		}
+		for i := range test.x {
+			if logSoftmax[i] != test.logSoftmax[i] && !floatsEqual(logSoftmax[i], test.logSoftmax[i]) {
+				t.Errorf("LogSoftmax(%v) = %v, expected %v", test.x, logSoftmax, test.logSoftmax)
+				break
+			}
+		}
+	}
+
+	// Working in place
+	x := []float64{1, 2, 3}
+	if Softmax(x, x); !floatsEqual(x[0]+x[1]+x[2], 1) {
+		t.Errorf("in-place softmax doesn't add up to 1: %v", x)
+	}
+}
+
+func TestSoftmaxCrossEntropy(t *testing.T) {
+	grad := make([]float64, 2)
+
+	loss := SoftmaxCrossEntropy([]float64{1000, -1000}, []float64{0, 1}, grad)
+	if loss != 2000 || grad[0] != 1 || grad[1] != -1 {
+		t.Errorf("got a loss of %g and gradient %v for confidently wrong logits", loss, grad)
+	}
+
+	loss = SoftmaxCrossEntropy([]float64{-1000, 1000}, []float64{0, 1}, grad)
+	if loss != 0 || grad[0] != 0 || grad[1] != 0 {
+		t.Errorf("got a loss of %g and gradient %v for confidently right logits", loss, grad)
+	}
+
+	// Check the gradient against finite differences
+	logits, target, eps := []float64{0.3, -1.2, 2, 0.1}, []float64{0.1, 0.2, 0.3, 0.4}, 1e-6
+	grad = make([]float64, len(logits))
+	SoftmaxCrossEntropy(logits, target, grad)
+	for i, orig := range logits {
+		logits[i] = orig + eps
+		lossPlus := SoftmaxCrossEntropy(logits, target, nil)
+		logits[i] = orig - eps
+		lossMinus := SoftmaxCrossEntropy(logits, target, nil)
+		logits[i] = orig
+
+		if relErr := relativeError(grad[i], (lossPlus-lossMinus)/(2*eps)); relErr > 1e-6 {
+			t.Errorf("gradient mismatch for logit %d: relative error %g", i, relErr)
+		}
+	}
+}
+
+func TestSigmoidCrossEntropy(t *testing.T) {
+	tests := []struct {
+		logit, target, loss, grad float64
+	}{
+		{0, 1, math.Ln2, -0.5},
+		{-1000, 1, 1000, -1},
+		{1000, 0, 1000, 1},
+		{1000, 1, 0, 0},
+		{-1000, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		loss, grad := SigmoidCrossEntropy(test.logit, test.target)
+		if !finiteSlice([]float64{loss, grad}) || (loss != test.loss && !floatsEqual(loss, test.loss)) || grad != test.grad {
+			t.Errorf("SigmoidCrossEntropy(%g, %g) = %g, %g, expected %g, %g", test.logit, test.target, loss, grad, test.loss, test.grad)
+		}
+	}
+
+	// It should match the naive formula where the latter is accurate
+	for _, z := range []float64{-3, -0.5, 0.5, 3} {
+		for _, target := range []float64{0, 0.3, 1} {
+			s := 1 / (1 + math.Exp(-z))
+			want := -target*math.Log(s) - (1-target)*math.Log(1-s)
+			if got, _ := SigmoidCrossEntropy(z, target); relativeError(got, want) > 1e-12 {
+				t.Errorf("SigmoidCrossEntropy(%g, %g) = %g, expected %g", z, target, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package mlp
+
+import "github.com/pcolladosoto/mlp-go/mlp/metrics"
+
+// TrainingMetrics tracks how training goes.
+type TrainingMetrics struct {
+	Steps    *metrics.Counter
+	Samples  *metrics.Counter
+	Epoch    *metrics.Gauge
+	Metrics  *metrics.Gauge
+	GradNorm *metrics.Histogram
+}
+
+// NewTrainingMetrics registers the training metrics with r.
+func NewTrainingMetrics(r *metrics.Registry) *TrainingMetrics {
+	return &TrainingMetrics{
+		Steps:   r.NewCounter("mlp_training_steps_total", "Training steps taken."),
+		Samples: r.NewCounter("mlp_training_samples_total", "Samples trained on."),
+		Epoch:   r.NewGauge("mlp_training_epoch", "The epoch of the last record."),
+		Metrics: r.NewGauge("mlp_training_metric", "The value of each metric, loss and val_loss included, in the last record.", "name"),
+		GradNorm: r.NewHistogram("mlp_training_grad_norm", "The global L2 norm of the gradients applied in each step, after clipping.",
+			metrics.ExponentialBuckets(1e-4, 10, 9)),
+	}
+}
+
+// ObserveStep records a training step on the given number of samples.
+func (m *TrainingMetrics) ObserveStep(mlp *Mlp, samples int) {
+	m.ObserveSteps(1, samples)
+	m.GradNorm.Observe(mlp.GradNorm())
+}
+
+// ObserveSteps records training steps on the given number of samples without
+// observing their gradients, as AdaptAsync keeps those to its workers.
+func (m *TrainingMetrics) ObserveSteps(steps, samples int) {
+	m.Steps.Add(float64(steps))
+	m.Samples.Add(float64(samples))
+}
+
+// ObserveRecord exports the metrics in a record taken by a Recorder.
+func (m *TrainingMetrics) ObserveRecord(rec Record) {
+	m.Epoch.Set(float64(rec.Epoch))
+	for name, v := range rec.Metrics {
+		m.Metrics.Set(v, name)
+	}
+}
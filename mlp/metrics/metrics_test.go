@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests.", "path")
+	g := r.NewGauge("temperature", "Line one.\nLine two.")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1})
+
+	c.Inc(`/a"b\`)
+	c.Add(2, `/a"b\`)
+	g.Set(-1.5)
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v)
+	}
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("WriteTo() returned an error: %v", err)
+	}
+	want := `# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{path="/a\"b\\"} 3
+# HELP temperature Line one.\nLine two.
+# TYPE temperature gauge
+temperature -1.5
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+`
+	if b.String() != want {
+		t.Errorf("wrong exposition:\n%s\nexpected:\n%s", b.String(), want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("WriteTo() reported %d bytes, but wrote %d", n, len(want))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a metric twice didn't panic")
+		}
+	}()
+	r.NewGauge("temperature", "Again.")
+}
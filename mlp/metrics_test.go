@@ -0,0 +1,53 @@
+package mlp
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pcolladosoto/mlp-go/mlp/metrics"
+)
+
+func TestTrainingMetrics(t *testing.T) {
+	xorData, _, targets := xorTargets(100)
+	m, err := NewMlpWith([]int{2, 4, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	tr, err := NewTrainer(20, 1, 0.5, 1)
+	if err != nil {
+		t.Fatalf("NewTrainer() returned an error: %v", err)
+	}
+
+	r := metrics.NewRegistry()
+	tr.Metrics = NewTrainingMetrics(r)
+	rec := Recorder{ValInputs: xorData[80:], ValTargets: targets[80:]}
+	h, err := tr.Fit(m, xorData[:80], targets[:80], 3, &rec)
+	if err != nil {
+		t.Fatalf("Fit() returned an error: %v", err)
+	}
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() returned an error: %v", err)
+	}
+	exposition := b.String()
+
+	last := h.Records[len(h.Records)-1]
+	for name, want := range map[string]float64{
+		`mlp_training_steps_total`:                 12,
+		`mlp_training_samples_total`:               240,
+		`mlp_training_epoch`:                       3,
+		`mlp_training_grad_norm_count`:             12,
+		`mlp_training_grad_norm_bucket{le="+Inf"}`: 12,
+		`mlp_training_metric{name="loss"}`:         last.Metrics["loss"],
+		`mlp_training_metric{name="val_loss"}`:     last.Metrics["val_loss"],
+	} {
+		if line := name + " " + strconv.FormatFloat(want, 'g', -1, 64) + "\n"; !strings.Contains(exposition, line) {
+			t.Errorf("the metrics lack %q:\n%s", line, exposition)
+		}
+	}
+	if strings.Contains(exposition, "mlp_training_grad_norm_sum 0\n") {
+		t.Errorf("no gradient norms were observed")
+	}
+}
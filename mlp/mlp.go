@@ -1,12 +1,10 @@
 package mlp
 
 import (
-	"bytes"
-	"encoding/base64"
 	"fmt"
-	"io/ioutil"
 	"math"
 	"math/rand"
+	"reflect"
 	"time"
 
 	"gonum.org/v1/gonum/mat"
@@ -17,18 +15,79 @@ type Mlp struct {
 	HiddenDim []int
 	NHidden   int
 	OutDim    int
-	ActFunc   func(float64) float64
-	Weights   []mat.Matrix
+	ActFunc   Activation
+	Weights   []*mat.Dense
+
+	Regularization Regularization
+	GradClip       GradClip
+
+	// Dropout holds the dropout rate of each hidden layer's output. Dropout
+	// only kicks in while Training is set: otherwise forward passes are
+	// deterministic.
+	Dropout  []float64
+	Training bool
+
+	// Norms holds the normalisation of each hidden layer's net activations,
+	// being nil for those without one. Check SetNorm.
+	Norms []*Norm
+
+	// rng drives dropout
+	rng *rand.Rand
+
+	// seq runs the passes of the MLP: check model. Single samples go through
+	// in and grad and mini-batches through batchIn and batchGrad, whereas acts
+	// and nets hold what ComputeActivation returns.
+	seq                *Sequential
+	layers             []mlpLayer
+	in, grad           *mat.Dense
+	batchIn, batchGrad *mat.Dense
+	acts, nets         []*mat.Dense
 }
 
+// mlpLayer holds the layers of seq making up each layer of the MLP together
+// with the indices of the ones whose output is its net activation and its
+// activation.
+type mlpLayer struct {
+	dense          *Dense
+	norm           *Norm
+	act            *ActivationLayer
+	drop           *Dropout
+	netIdx, actIdx int
+}
+
+// NewMlp returns an MLP applying Sigmoid, ReLu or UnitStep, which is how
+// earlier versions built them. Back-propagation needs the derivative of the
+// activation function, so any other one should come as an Activation through
+// NewMlpWith.
 func NewMlp(dims []int, actF func(float64) float64, variance float64) (*Mlp, error) {
+	for _, act := range Activations {
+		if reflect.ValueOf(act.F).Pointer() == reflect.ValueOf(actF).Pointer() {
+			return NewMlpWith(dims, act, variance)
+		}
+	}
+	return nil, fmt.Errorf("NewMlp only knows the derivatives of Sigmoid, ReLu and UnitStep: use NewMlpWith for other activation functions")
+}
+
+// NewMlpWith returns an MLP with the given dimensions, from the input's to the
+// output's, applying actF after every layer. Its weights are drawn from a
+// normal distribution with the given variance.
+func NewMlpWith(dims []int, actF Activation, variance float64) (*Mlp, error) {
 	if len(dims) < 3 {
 		return nil, fmt.Errorf("we need at least 3 dimensions for the input, hidden and output layer")
 	}
+	for i, d := range dims {
+		if d <= 0 {
+			return nil, fmt.Errorf("every layer needs at least a neuron, but dimension %d is %d", i, d)
+		}
+	}
 
-	mlp := Mlp{InDim: dims[0], HiddenDim: dims[1 : len(dims)-1], NHidden: len(dims) - 2, OutDim: dims[len(dims)-1], ActFunc: actF}
+	mlp := Mlp{
+		InDim: dims[0], HiddenDim: dims[1 : len(dims)-1], NHidden: len(dims) - 2, OutDim: dims[len(dims)-1], ActFunc: actF,
+		Training: true,
+	}
 
 	rand.Seed(time.Now().Unix())
+	mlp.rng = rand.New(rand.NewSource(rand.Int63()))
 
 	// Let's avoid recomputing the standard deviation over and over
 	stdDev := math.Sqrt(variance)
@@ -39,14 +98,91 @@ func NewMlp(dims []int, actF func(float64) float64, variance float64) (*Mlp, err
 		}
 		mlp.Weights = append(mlp.Weights, mat.NewDense(dims[i+1], dims[i]+1, weights))
 	}
+	mlp.model()
 
 	return &mlp, nil
 }
 
+// model returns the Sequential model running the passes of the MLP, building
+// it anew whenever the weight matrices or normalisations are swapped.
+func (mlp *Mlp) model() *Sequential {
+	if !mlp.upToDate() {
+		mlp.seq, mlp.layers = NewSequential(), make([]mlpLayer, len(mlp.Weights))
+		for i, w := range mlp.Weights {
+			l := &mlp.layers[i]
+
+			l.dense = &Dense{W: w}
+			mlp.seq.Layers = append(mlp.seq.Layers, l.dense)
+			if l.norm = mlp.norm(i); l.norm != nil {
+				mlp.seq.Layers = append(mlp.seq.Layers, l.norm)
+			}
+			l.netIdx = len(mlp.seq.Layers) - 1
+
+			l.act = NewActivationLayer(mlp.ActFunc)
+			mlp.seq.Layers = append(mlp.seq.Layers, l.act)
+			if i < len(mlp.Weights)-1 {
+				l.drop = NewDropout(0, mlp.rng)
+				mlp.seq.Layers = append(mlp.seq.Layers, l.drop)
+			}
+			l.actIdx = len(mlp.seq.Layers) - 1
+		}
+
+		mlp.in, mlp.grad = mat.NewDense(1, mlp.InDim, nil), mat.NewDense(1, mlp.OutDim, nil)
+		mlp.acts, mlp.nets = make([]*mat.Dense, len(mlp.Weights)), make([]*mat.Dense, len(mlp.Weights))
+	}
+
+	// These can change without rebuilding anything
+	for i := range mlp.layers {
+		l := &mlp.layers[i]
+		l.act.Act = mlp.ActFunc
+		if l.drop != nil {
+			l.drop.Rate = mlp.dropoutRate(i)
+		}
+	}
+
+	return mlp.seq
+}
+
+// upToDate checks whether the model still reflects the weight matrices and
+// normalisations of the MLP.
+func (mlp *Mlp) upToDate() bool {
+	if mlp.seq == nil || len(mlp.layers) != len(mlp.Weights) {
+		return false
+	}
+	for i, l := range mlp.layers {
+		if l.dense.W != mlp.Weights[i] || l.norm != mlp.norm(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// params returns every trainable parameter in the order the model holds them.
+func (mlp *Mlp) params() []*mat.Dense {
+	return mlp.model().Params()
+}
+
+// SetSeed reseeds the random number generator behind dropout so that training
+// can be reproduced.
+func (mlp *Mlp) SetSeed(seed int64) {
+	mlp.rng.Seed(seed)
+}
+
+// dropoutRate returns the dropout rate for the output of layer i.
+func (mlp *Mlp) dropoutRate(i int) float64 {
+	if i >= len(mlp.Dropout) || i >= len(mlp.Weights)-1 {
+		return 0
+	}
+	return mlp.Dropout[i]
+}
+
+// SetWeights replaces the weight matrices with copies of the given ones, laid
+// out row after row. Training updates the weights in place, so the MLP never
+// holds on to the caller's slices.
 func (mlp *Mlp) SetWeights(init_ws [][]float64) {
 	for i, w := range init_ws {
 		r, c := mlp.Weights[i].Dims()
-		mlp.Weights[i] = mat.NewDense(r, c, w)
+		mlp.Weights[i] = mat.NewDense(r, c, append([]float64(nil), w...))
 	}
 }
 
@@ -54,120 +190,96 @@ func (mlp *Mlp) String() string {
 	msg := fmt.Sprintf("MLP Description:\n\tDimensions       -> %v / %v / %v\n", mlp.InDim, mlp.HiddenDim, mlp.OutDim)
 	for i, w := range mlp.Weights {
 		msg += fmt.Sprintf("\tWeight Matrix %2d -> %v\n", i, mat.Formatted(w, mat.FormatMATLAB()))
+		if norm := mlp.norm(i); norm != nil {
+			msg += fmt.Sprintf("\tNormalisation %2d -> %s\n", i, norm.Kind)
+		}
 	}
 	return msg
 }
 
-func (mlp *Mlp) appendRow(m *mat.Dense, n float64) *mat.Dense {
-	rawM := m.RawMatrix()
-	return mat.NewDense(m.RawMatrix().Rows+1, rawM.Cols, append(rawM.Data, n))
-}
-
-func (mlp *Mlp) chopRow(m *mat.Dense) *mat.Dense {
-	rawM := m.RawMatrix()
-	return mat.NewDense(m.RawMatrix().Rows-1, rawM.Cols, rawM.Data[:len(rawM.Data)-1])
-}
-
+// ComputeActivation runs a forward pass on input. The returned slices are backed
+// by the MLP's buffers: they're only valid until the next call, and the MLP
+// mustn't be used from several goroutines at once. Predictor takes a snapshot
+// that can.
 func (mlp *Mlp) ComputeActivation(input []float64) (output []float64, activations []*mat.Dense, net_activations []*mat.Dense) {
-	var net_acts, acts []*mat.Dense
-
-	acts = append(acts, mat.NewDense(mlp.InDim, 1, input))
-
-	for i, w := range mlp.Weights {
-		tmpA := mlp.appendRow(acts[i], 1)
-
-		// fmt.Printf("Multiplying %v * %v\n", mat.Formatted(w, mat.FormatMATLAB()), mat.Formatted(tmpA, mat.FormatMATLAB()))
-		// fmt.Printf("Dimensionality:\n\tWeights -> %s\n\tActs    -> %s\n", fmt.Sprint(w.Dims()), fmt.Sprint(tmpA.Dims()))
-
-		var tmp mat.Dense
-		tmp.Mul(w, tmpA)
+	seq := mlp.model()
+	out := mlp.forwardSample(seq, mlp.in, input)
 
-		net_acts = append(net_acts, mat.DenseCopyOf(&tmp))
-
-		acts = append(acts, new(mat.Dense))
-
-		acts[i+1].Apply(func(i, j int, v float64) float64 { return mlp.ActFunc(v) }, &tmp)
+	for i, l := range mlp.layers {
+		mlp.acts[i] = columnView(mlp.acts[i], seq.Output(l.actIdx))
+		mlp.nets[i] = columnView(mlp.nets[i], seq.Output(l.netIdx))
 	}
 
-	return acts[len(acts)-1].RawMatrix().Data, acts[1:], net_acts
+	return out.RawRowView(0), mlp.acts, mlp.nets
 }
 
 func (mlp *Mlp) Adapt(input, target []float64, learning_rate float64) {
-	_, acts, _ := mlp.ComputeActivation(input)
-
-	// Reverse the activations
-	for i, j := 0, len(acts)-1; i < j; i, j = i+1, j-1 {
-		acts[i], acts[j] = acts[j], acts[i]
-	}
-
-	delta_helper := func(a, b *mat.Dense) *mat.Dense {
-		var tmp mat.Dense
-		tmp.Apply(func(i, j int, v float64) float64 { return 1 - v }, b)
-		tmp.MulElem(&tmp, b)
-		tmp.MulElem(&tmp, a)
-		return &tmp
-	}
-
-	var (
-		deltas []*mat.Dense
-		tmp    mat.Dense
-	)
-
-	// fmt.Printf("\nBeginning adaptation with learning rate = %1.3f\n", learning_rate)
-
-	acts = append(acts, mat.NewDense(mlp.InDim, 1, input))
-	tmp.Sub(acts[0], mat.NewDense(mlp.OutDim, 1, target))
-	deltas = append(deltas, delta_helper(&tmp, acts[0]))
-
-	// fmt.Printf("\tdeltas[00] -> %7.4f\n", mat.Formatted(deltas[0], mat.FormatMATLAB()))
-
-	for i := range mlp.Weights {
-		var updated_weights, tmp_delta mat.Dense
-		updated_weights.Mul(deltas[i], mlp.appendRow(acts[i+1], 1).T())
-		updated_weights.Apply(func(i, j int, v float64) float64 { return learning_rate * v }, &updated_weights)
-
-		// fmt.Printf("\t\tWeigth Matrix variation for layer %2d -> %6.3f\n",
-		// 	len(mlp.Weights)-i, mat.Formatted(&updated_weights, mat.FormatMATLAB()))
-
-		updated_weights.Sub(mlp.Weights[len(mlp.Weights)-(i+1)], &updated_weights)
-
-		// tmp_delta.Mul(updated_weights.T(), deltas[i])
-		tmp_delta.Mul(mlp.Weights[len(mlp.Weights)-(i+1)].T(), deltas[i])
+	seq := mlp.model()
+	mlp.adaptSample(seq, mlp.in, mlp.grad, input, target, learning_rate)
+}
 
-		deltas = append(deltas, delta_helper(mlp.chopRow(&tmp_delta), acts[i+1]))
+// adaptSample applies Adapt's update through seq, relying on x and grad as
+// the buffers for the input and the derivative of the loss.
+func (mlp *Mlp) adaptSample(seq *Sequential, x, grad *mat.Dense, input, target []float64, learning_rate float64) {
+	out := mlp.forwardSample(seq, x, input)
+	lossGrad(grad.RawRowView(0), out.RawRowView(0), target, 1)
+	seq.Backward(grad)
+	mlp.update(seq, learning_rate)
+}
 
-		// fmt.Printf("\tdeltas[%02d] -> %7.4f\n", i+1, mat.Formatted(deltas[i+1], mat.FormatMATLAB()))
-		// fmt.Printf("\t\tWeigth Matrix %2d before adapting     -> %6.3f\n",
-		// 	len(mlp.Weights)-(i+1), mat.Formatted(mlp.Weights[len(mlp.Weights)-(i+1)], mat.FormatMATLAB()))
+// forwardSample runs a forward pass on input through seq, copying it into x.
+func (mlp *Mlp) forwardSample(seq *Sequential, x *mat.Dense, input []float64) *mat.Dense {
+	copy(x.RawRowView(0), input)
+	return seq.Forward(x, mlp.Training)
+}
 
-		mlp.Weights[len(mlp.Weights)-(i+1)] = mat.DenseCopyOf(&updated_weights)
+// forwardBatch runs a forward pass on the mini-batch made up of inputs.
+func (mlp *Mlp) forwardBatch(seq *Sequential, inputs [][]float64, training bool) *mat.Dense {
+	mlp.batchIn = reuse(mlp.batchIn, len(inputs), mlp.InDim)
+	for s, input := range inputs {
+		copy(mlp.batchIn.RawRowView(s), input)
+	}
+	return seq.Forward(mlp.batchIn, training)
+}
 
-		// fmt.Printf("\t\tWeigth Matrix %2d after adapting      -> %6.3f\n",
-		// 	len(mlp.Weights)-(i+1), mat.Formatted(mlp.Weights[len(mlp.Weights)-(i+1)], mat.FormatMATLAB()))
+// lossGrad fills grad with the derivative of the squared error between output
+// and target scaled by the given factor.
+func lossGrad(grad, output, target []float64, scale float64) {
+	for j, o := range output {
+		grad[j] = (o - target[j]) * scale
 	}
 }
 
-func (mlp *Mlp) GenTestData() {
-	mlp.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+// update applies the gradient descent step for the gradients computed by the
+// last backward pass through seq, clipping and regularization included.
+func (mlp *Mlp) update(seq *Sequential, learning_rate float64) {
+	mlp.clip(seq)
 
-	_, acts, net_acts := mlp.ComputeActivation([]float64{1, 0})
+	for _, l := range seq.Layers {
+		d, isDense := l.(*Dense)
+		if isDense {
+			mlp.Regularization.decay(d.W, learning_rate)
+		}
 
-	acts_buff, net_acts_buff := bytes.Buffer{}, bytes.Buffer{}
+		grads := l.Grads()
+		for k, p := range l.Params() {
+			sgdStep(p, grads[k], learning_rate)
+		}
 
-	fmt.Printf("Activations:\n")
-	for i, act := range acts {
-		fmt.Printf("\tActivation     %2d -> %6.3f\n", i, mat.Formatted(act, mat.FormatMATLAB()))
-		act.MarshalBinaryTo(&acts_buff)
+		if isDense {
+			mlp.Regularization.constrain(d.W)
+		}
 	}
+}
 
-	fmt.Printf("\nNet Activations:\n")
-	for i, net_act := range net_acts {
-		fmt.Printf("\tNet Activation %2d -> %6.3f\n", i, mat.Formatted(net_act, mat.FormatMATLAB()))
-		net_act.MarshalBinaryTo(&net_acts_buff)
+// columnView returns the row vector m as a column one, reusing view if it
+// already is one.
+func columnView(view, m *mat.Dense) *mat.Dense {
+	data := m.RawRowView(0)
+	if view != nil && sameStorage(view, data) {
+		return view
 	}
-
-	ioutil.WriteFile("testdata/act_data.b64", []byte(base64.StdEncoding.EncodeToString(acts_buff.Bytes())), 0644)
-	ioutil.WriteFile("testdata/net_act_data.b64", []byte(base64.StdEncoding.EncodeToString(net_acts_buff.Bytes())), 0644)
+	return mat.NewDense(len(data), 1, data)
 }
 
 func ErrorRate(predictions, labels []float64) float64 {
@@ -3,16 +3,19 @@ package mlp
 import (
 	"bytes"
 	"encoding/base64"
+	"flag"
 	"io/ioutil"
+	"math"
+	"path/filepath"
 	"testing"
 
 	"gonum.org/v1/gonum/mat"
 )
 
 func TestWeightInit(t *testing.T) {
-	m, err := NewMlp([]int{2, 2, 1}, Sigmoid, 1)
+	m, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
 	if err != nil {
-		t.Errorf("NewMlp() returned an error: %v", err)
+		t.Errorf("NewMlpWith() returned an error: %v", err)
 	}
 
 	init_weights := [][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}}
@@ -29,64 +32,218 @@ func TestWeightInit(t *testing.T) {
 	}
 }
 
+// Run the tests with -update to regenerate the golden files under testdata/
+// after an intended change in behaviour.
+var update = flag.Bool("update", false, "regenerate the golden files instead of checking against them")
+
+// checkGolden compares got with the matrices stored in a golden file under
+// testdata/ or overwrites the file with them when running with -update. Values
+// only have to match up to rounding errors so that rewriting the maths in an
+// equivalent way doesn't call for new golden files.
+func checkGolden(t *testing.T, fname string, got []*mat.Dense) {
+	t.Helper()
+
+	fpath := filepath.Join("testdata", fname)
+
+	if *update {
+		var buff bytes.Buffer
+		for _, m := range got {
+			if _, err := m.MarshalBinaryTo(&buff); err != nil {
+				t.Fatalf("error marshalling a matrix: %v", err)
+			}
+		}
+		if err := ioutil.WriteFile(fpath, []byte(base64.StdEncoding.EncodeToString(buff.Bytes())), 0644); err != nil {
+			t.Fatalf("error writing golden file %s: %v", fpath, err)
+		}
+		return
+	}
+
+	raw, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("error opening golden file %s: %v", fpath, err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(raw))
+	if err != nil {
+		t.Fatalf("error decoding golden file %s: %v", fpath, err)
+	}
+
+	buff := bytes.NewBuffer(decoded)
+	for i, m := range got {
+		var want mat.Dense
+		if _, err := want.UnmarshalBinaryFrom(buff); err != nil {
+			t.Fatalf("error unmarshalling matrix %d from %s: %v", i, fpath, err)
+		}
+
+		if !densesEqual(m, &want) {
+			t.Errorf("matrix %d mismatch against %s: %6.3f != %6.3f", i, fpath,
+				mat.Formatted(m, mat.FormatMATLAB()), mat.Formatted(&want, mat.FormatMATLAB()))
+		}
+	}
+	if buff.Len() != 0 {
+		t.Errorf("%s holds more matrices than expected", fpath)
+	}
+}
+
+// densesEqual checks whether a and b have the same shape and values up to
+// rounding errors.
+func densesEqual(a, b *mat.Dense) bool {
+	r, c := a.Dims()
+	if br, bc := b.Dims(); r != br || c != bc {
+		return false
+	}
+	for i := 0; i < r; i++ {
+		for j := 0; j < c; j++ {
+			if !floatsEqual(a.At(i, j), b.At(i, j)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestNewMlpInvalid(t *testing.T) {
+	for _, dims := range [][]int{{2, 1}, {2, 0, 1}, {0, 2, 1}, {2, 3, -1}} {
+		if _, err := NewMlpWith(dims, SigmoidAct, 1); err == nil {
+			t.Errorf("NewMlpWith() accepted the dimensions %v", dims)
+		}
+	}
+}
+
+func TestNewMlp(t *testing.T) {
+	for name, act := range Activations {
+		m, err := NewMlp([]int{2, 3, 1}, act.F, 1)
+		if err != nil {
+			t.Fatalf("NewMlp() returned an error for %s: %v", name, err)
+		}
+		if m.ActFunc.Name != name {
+			t.Errorf("NewMlp() picked %s instead of %s", m.ActFunc.Name, name)
+		}
+	}
+
+	if _, err := NewMlp([]int{2, 3, 1}, math.Tanh, 1); err == nil {
+		t.Errorf("NewMlp() accepted an activation function without a known derivative")
+	}
+}
+
 func TestForwardPropagation(t *testing.T) {
-	m, err := NewMlp([]int{2, 2, 1}, Sigmoid, 1)
+	m, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
 	if err != nil {
-		t.Errorf("NewMlp() returned an error: %v", err)
+		t.Errorf("NewMlpWith() returned an error: %v", err)
 	}
 
 	m.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
 
-	var buff bytes.Buffer
-
 	_, acts, net_acts := m.ComputeActivation([]float64{1, 0})
 
-	ref_acts_raw, err := ioutil.ReadFile("testdata/act_data.b64")
+	checkGolden(t, "act_data.b64", acts)
+	checkGolden(t, "net_act_data.b64", net_acts)
+}
+
+func TestAdapt(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
 	if err != nil {
-		t.Fatalf("error opening reference data for activations: %v", err)
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
 	}
 
-	ref_acts, err := base64.StdEncoding.DecodeString(string(ref_acts_raw))
-	if err != nil {
-		t.Fatalf("error decoding reference data for activations: %v", err)
+	init_weights := [][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}}
+	m.SetWeights(init_weights)
+
+	m.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+	if init_weights[0][0] != 6 || init_weights[1][0] != -4 {
+		t.Errorf("adapting changed the weights given to SetWeights: %v", init_weights)
 	}
 
-	buff.Write(ref_acts)
+	// With s(x) being the sigmoid, the forward pass yields:
+	//   a1 = [s(4); s(2)] = [0.98201379; 0.88079708]
+	//   a2 = s(-4 * 0.98201379 + 2 * 0.88079708 + 2) = s(-0.16646100) = 0.45848058
+	// The deltas for the output and hidden layers are then:
+	//   d2 = (a2 - 1) * a2 * (1 - a2) = -0.13444635
+	//   d1 = [-4; 2] * d2 .* a1 .* (1 - a1) = [0.00949875; -0.02823201]
+	// And each weight matrix is updated with W - 0.5 * d * [a; 1]'.
+	want := []*mat.Dense{
+		mat.NewDense(2, 3, []float64{
+			6 - 0.5*0.009498745571461518, 0, -2 - 0.5*0.009498745571461518,
+			2 + 0.5*0.028232008796957624, -2, 0 + 0.5*0.028232008796957624,
+		}),
+		mat.NewDense(1, 3, []float64{
+			-4 + 0.5*0.13444635064350666*0.9820137900379085,
+			2 + 0.5*0.13444635064350666*0.8807970779778823,
+			2 + 0.5*0.13444635064350666,
+		}),
+	}
 
-	for _, act := range acts {
-		var tmp mat.Dense
-		if _, err := tmp.UnmarshalBinaryFrom(&buff); err != nil {
-			t.Fatalf("error unmarshalling an activation matrix: %v", err)
+	for i, w := range m.Weights {
+		if !mat.EqualApprox(w, want[i], 1e-12) {
+			t.Errorf("weight matrix %d mismatch after adapting: %.8f != %.8f",
+				i, mat.Formatted(w, mat.FormatMATLAB()), mat.Formatted(want[i], mat.FormatMATLAB()))
 		}
+	}
+}
 
-		if !mat.Equal(act, &tmp) {
-			t.Errorf("activation matrix mismatch: %6.3f != %6.3f",
-				mat.Formatted(act, mat.FormatMATLAB()), mat.Formatted(&tmp, mat.FormatMATLAB()))
-		}
+func TestAdaptGolden(t *testing.T) {
+	for name, act := range Activations {
+		t.Run(name, func(t *testing.T) {
+			m, err := NewMlpWith([]int{3, 4, 3, 2}, act, 1)
+			if err != nil {
+				t.Fatalf("NewMlpWith() returned an error: %v", err)
+			}
+
+			rng := randomiseWeights(m, 1)
+
+			for i := 0; i < 5; i++ {
+				m.Adapt([]float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()},
+					[]float64{rng.Float64(), rng.Float64()}, 0.1)
+			}
+
+			checkGolden(t, "adapt_"+name+".b64", m.Weights)
+		})
 	}
+}
 
-	ref_net_acts_raw, err := ioutil.ReadFile("testdata/net_act_data.b64")
+func TestZeroAllocs(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
 	if err != nil {
-		t.Fatalf("error opening reference data for net activations: %v", err)
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	input, target := []float64{1, 0}, []float64{1}
+
+	if allocs := testing.AllocsPerRun(100, func() { m.ComputeActivation(input) }); allocs != 0 {
+		t.Errorf("ComputeActivation() allocated %.1f times per run", allocs)
 	}
 
-	ref_net_acts, err := base64.StdEncoding.DecodeString(string(ref_net_acts_raw))
+	if allocs := testing.AllocsPerRun(100, func() { m.Adapt(input, target, 0.05) }); allocs != 0 {
+		t.Errorf("Adapt() allocated %.1f times per run", allocs)
+	}
+}
+
+func BenchmarkComputeActivation(b *testing.B) {
+	m, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
 	if err != nil {
-		t.Fatalf("error decoding reference data for net activations: %v", err)
+		b.Fatalf("NewMlpWith() returned an error: %v", err)
 	}
 
-	buff.Reset()
-	buff.Write(ref_net_acts)
+	xorData, _ := GenXor(b.N, 0.1)
 
-	for _, net_act := range net_acts {
-		var tmp mat.Dense
-		if _, err := tmp.UnmarshalBinaryFrom(&buff); err != nil {
-			t.Fatalf("error unmarshalling a net activation matrix: %v", err)
-		}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.ComputeActivation(xorData[i])
+	}
+}
 
-		if !mat.Equal(net_act, &tmp) {
-			t.Errorf("net activation matrix mismatch: %6.3f != %6.3f",
-				mat.Formatted(net_act, mat.FormatMATLAB()), mat.Formatted(&tmp, mat.FormatMATLAB()))
-		}
+func BenchmarkAdapt(b *testing.B) {
+	m, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
+	if err != nil {
+		b.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	xorData, xorLabels := GenXor(b.N, 0.1)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Adapt(xorData[i], xorLabels[i:i+1], 0.05)
 	}
 }
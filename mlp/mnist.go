@@ -7,6 +7,7 @@ import (
 	"image/png"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/nfnt/resize"
 )
@@ -122,22 +123,83 @@ func ReadImgs(fpath string) (Images, error) {
 	}
 }
 
-func DumpImage(imgs Images, index int, fname string) error {
-	img := image.NewRGBA(image.Rect(0, 0, int(imgs.ImgCols), int(imgs.ImgRows)))
+// Interpolations maps the names of the interpolation functions images can be
+// resized with to the functions themselves.
+var Interpolations = map[string]resize.InterpolationFunction{
+	"nearest":  resize.NearestNeighbor,
+	"bilinear": resize.Bilinear,
+	"bicubic":  resize.Bicubic,
+	"mitchell": resize.MitchellNetravali,
+	"lanczos2": resize.Lanczos2,
+	"lanczos3": resize.Lanczos3,
+}
+
+// ImageOptions controls how images are exported.
+type ImageOptions struct {
+	// Size of the output in pixels
+	Width, Height uint
+
+	// Interpolation is one of the keys of Interpolations
+	Interpolation string
+
+	// Invert draws the digits in black over a white background instead of
+	// the other way around.
+	Invert bool
+}
 
+// DefaultImageOptions are the options DumpImage relies on.
+var DefaultImageOptions = ImageOptions{Width: 100, Height: 100, Interpolation: "lanczos3", Invert: true}
+
+// Image returns the image at the given index resized as set in opts.
+func (imgs Images) Image(index int, opts ImageOptions) (image.Image, error) {
+	if index < 0 || index >= len(imgs.Images) {
+		return nil, fmt.Errorf("image index %d out of range [0, %d)", index, len(imgs.Images))
+	}
+	interp, ok := Interpolations[strings.ToLower(opts.Interpolation)]
+	if !ok {
+		return nil, fmt.Errorf("unknown interpolation %q", opts.Interpolation)
+	}
+	if opts.Width == 0 || opts.Height == 0 {
+		return nil, fmt.Errorf("wrong image size %d x %d", opts.Width, opts.Height)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, int(imgs.ImgCols), int(imgs.ImgRows)))
 	for i, row := range imgs.Images[index] {
 		for j, px := range row {
-			img.SetRGBA(j, i, color.RGBA{255 - uint8(px*255), 255 - uint8(px*255), 255 - uint8(px*255), 255})
+			v := uint8(px * 255)
+			if opts.Invert {
+				v = 255 - v
+			}
+			img.SetGray(j, i, color.Gray{v})
 		}
 	}
 
-	rsz_img := resize.Resize(100, 100, img, resize.Lanczos3)
+	return resize.Resize(opts.Width, opts.Height, img, interp), nil
+}
+
+// DumpImage writes the image at the given index as a PNG with the
+// DefaultImageOptions.
+func DumpImage(imgs Images, index int, fname string) error {
+	return DumpImageWith(imgs, index, fname, DefaultImageOptions)
+}
 
+// DumpImageWith writes the image at the given index as a PNG.
+func DumpImageWith(imgs Images, index int, fname string, opts ImageOptions) error {
+	img, err := imgs.Image(index, opts)
+	if err != nil {
+		return err
+	}
+	return writePNG(img, fname)
+}
+
+func writePNG(img image.Image, fname string) error {
 	fd, err := os.Create(fname)
 	if err != nil {
 		return fmt.Errorf("couldn't create the image: %v", err)
 	}
-	defer fd.Close()
-
-	return png.Encode(fd, rsz_img)
+	if err := png.Encode(fd, img); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
 }
@@ -0,0 +1,40 @@
+package mlp
+
+import (
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpImageWith(t *testing.T) {
+	imgs := smallImages(2, 4, 4)
+	fpath := filepath.Join(t.TempDir(), "img.png")
+
+	opts := ImageOptions{Width: 8, Height: 12, Interpolation: "nearest"}
+	if err := DumpImageWith(imgs, 1, fpath, opts); err != nil {
+		t.Fatalf("DumpImageWith() returned an error: %v", err)
+	}
+	img := decodePNG(t, fpath)
+	if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 12 {
+		t.Errorf("wrong size: %v", b)
+	}
+
+	// Pixel (0, 0) of the second image is 1 / 2
+	plain := color.GrayModel.Convert(img.At(0, 0)).(color.Gray).Y
+
+	opts.Invert = true
+	if err := DumpImageWith(imgs, 1, fpath, opts); err != nil {
+		t.Fatalf("DumpImageWith() returned an error: %v", err)
+	}
+	inverted := color.GrayModel.Convert(decodePNG(t, fpath).At(0, 0)).(color.Gray).Y
+	if plain != 127 || inverted != 128 {
+		t.Errorf("wrong pixel values: %d plain and %d inverted", plain, inverted)
+	}
+
+	if err := DumpImage(imgs, 2, fpath); err == nil {
+		t.Errorf("DumpImage() accepted an out of range index")
+	}
+	if err := DumpImageWith(imgs, 0, fpath, ImageOptions{Width: 8, Height: 8, Interpolation: "sinc"}); err == nil {
+		t.Errorf("DumpImageWith() accepted an unknown interpolation")
+	}
+}
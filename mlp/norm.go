@@ -0,0 +1,315 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type NormKind int
+
+const (
+	NoNorm NormKind = iota
+	BatchNorm
+	LayerNorm
+)
+
+var normNames map[NormKind]string = map[NormKind]string{
+	NoNorm:    "none",
+	BatchNorm: "batch",
+	LayerNorm: "layer",
+}
+
+func (k NormKind) String() string {
+	if name, ok := normNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("NormKind(%d)", int(k))
+}
+
+func (k NormKind) MarshalText() ([]byte, error) {
+	if _, ok := normNames[k]; !ok {
+		return nil, fmt.Errorf("unknown normalisation %d", int(k))
+	}
+	return []byte(k.String()), nil
+}
+
+func (k *NormKind) UnmarshalText(text []byte) error {
+	for kind, name := range normNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown normalisation %q: choose one of [none, batch, layer]", text)
+}
+
+// Norm normalises the net activations of a hidden layer before they go
+// through the activation function, scaling and shifting the result by the
+// learned Gamma and Beta:
+//
+//	y = Gamma * (x - mean) / sqrt(variance + Eps) + Beta
+//
+// Layer normalisation computes the mean and variance over the neurons of the
+// layer for each sample. Batch normalisation computes them for each neuron over
+// the samples of a mini-batch, but only while training on more than one sample
+// at once such as through AdaptBatch or a Trainer: the rest of the time it
+// relies on the running estimates it keeps updating with Momentum, which
+// includes Adapt and AdaptAsync.
+type Norm struct {
+	Kind  NormKind
+	Gamma []float64
+	Beta  []float64
+
+	RunningMean []float64 `json:",omitempty"`
+	RunningVar  []float64 `json:",omitempty"`
+	Momentum    float64   `json:",omitempty"`
+
+	Eps float64
+
+	// xhat holds the normalised inputs before scaling and shifting for the
+	// last forward pass. Layer normalisation keeps the inverse standard
+	// deviation of each sample in invStd, whereas batch normalisation keeps
+	// the mean and inverse standard deviation of each neuron in mean and
+	// invStd, which come from the mini-batch when batchStats is set.
+	xhat, y, dx  *mat.Dense
+	mean, invStd []float64
+	batchStats   bool
+
+	gradGamma, gradBeta *mat.Dense
+	params, grads       []*mat.Dense
+}
+
+func NewNorm(kind NormKind, dim int) *Norm {
+	n := Norm{Kind: kind, Gamma: make([]float64, dim), Beta: make([]float64, dim), Eps: 1e-5}
+	for j := range n.Gamma {
+		n.Gamma[j] = 1
+	}
+
+	if kind == BatchNorm {
+		n.RunningMean, n.RunningVar, n.Momentum = make([]float64, dim), make([]float64, dim), 0.1
+		for j := range n.RunningVar {
+			n.RunningVar[j] = 1
+		}
+	}
+
+	return &n
+}
+
+// validate checks a restored normalisation is consistent and fits a layer of
+// dim neurons.
+func (n *Norm) validate(dim int) error {
+	if !(n.Eps > 0) {
+		return fmt.Errorf("%s normalisation should have a positive epsilon, but it's %v", n.Kind, n.Eps)
+	}
+	if len(n.Gamma) != dim || len(n.Beta) != dim {
+		return fmt.Errorf("%s normalisation should have %d parameters", n.Kind, dim)
+	}
+	if n.Kind == BatchNorm && (len(n.RunningMean) != dim || len(n.RunningVar) != dim) {
+		return fmt.Errorf("batch normalisation should have %d running statistics", dim)
+	}
+	return nil
+}
+
+// SetNorm normalises the output of the given hidden layer, counting from 0.
+// Choosing NoNorm removes any normalisation the layer had.
+func (mlp *Mlp) SetNorm(layer int, kind NormKind) error {
+	if layer < 0 || layer >= mlp.NHidden {
+		return fmt.Errorf("there's no hidden layer %d: there are just %d", layer, mlp.NHidden)
+	}
+	if _, ok := normNames[kind]; !ok {
+		return fmt.Errorf("unknown normalisation %d", int(kind))
+	}
+
+	for len(mlp.Norms) < mlp.NHidden {
+		mlp.Norms = append(mlp.Norms, nil)
+	}
+
+	if kind == NoNorm {
+		mlp.Norms[layer] = nil
+		return nil
+	}
+	mlp.Norms[layer] = NewNorm(kind, mlp.HiddenDim[layer])
+	return nil
+}
+
+// norm returns the normalisation of layer i's output, if any.
+func (mlp *Mlp) norm(i int) *Norm {
+	if i >= len(mlp.Norms) || i >= len(mlp.Weights)-1 {
+		return nil
+	}
+	return mlp.Norms[i]
+}
+
+func (n *Norm) Forward(x *mat.Dense, training bool) *mat.Dense {
+	m, dim := x.Dims()
+	n.xhat, n.y = reuse(n.xhat, m, dim), reuse(n.y, m, dim)
+
+	switch n.Kind {
+	case LayerNorm:
+		n.invStd = grow(n.invStd, m)
+		for i := 0; i < m; i++ {
+			row, xhat := x.RawRowView(i), n.xhat.RawRowView(i)
+
+			mean, variance := 0.0, 0.0
+			for _, v := range row {
+				mean += v
+			}
+			mean /= float64(dim)
+			for _, v := range row {
+				variance += (v - mean) * (v - mean)
+			}
+			variance /= float64(dim)
+
+			n.invStd[i] = 1 / math.Sqrt(variance+n.Eps)
+			for j, v := range row {
+				xhat[j] = (v - mean) * n.invStd[i]
+			}
+		}
+	case BatchNorm:
+		// A single sample has no spread to speak of
+		n.batchStats = training && m > 1
+
+		n.mean, n.invStd = grow(n.mean, dim), grow(n.invStd, dim)
+		for j := 0; j < dim; j++ {
+			if n.batchStats {
+				n.computeStats(x, j)
+			} else {
+				n.mean[j], n.invStd[j] = n.RunningMean[j], 1/math.Sqrt(n.RunningVar[j]+n.Eps)
+			}
+		}
+		for i := 0; i < m; i++ {
+			xhat := n.xhat.RawRowView(i)
+			for j, v := range x.RawRowView(i) {
+				xhat[j] = (v - n.mean[j]) * n.invStd[j]
+			}
+		}
+	}
+
+	for i := 0; i < m; i++ {
+		y := n.y.RawRowView(i)
+		for j, v := range n.xhat.RawRowView(i) {
+			y[j] = n.Gamma[j]*v + n.Beta[j]
+		}
+	}
+	return n.y
+}
+
+// computeStats fills the mean and inverse standard deviation of neuron j over
+// the mini-batch x and updates its running estimates.
+func (n *Norm) computeStats(x *mat.Dense, j int) {
+	rows, _ := x.Dims()
+	m := float64(rows)
+
+	mean, variance := 0.0, 0.0
+	for i := 0; i < rows; i++ {
+		mean += x.At(i, j)
+	}
+	mean /= m
+	for i := 0; i < rows; i++ {
+		v := x.At(i, j)
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= m
+
+	n.mean[j], n.invStd[j] = mean, 1/math.Sqrt(variance+n.Eps)
+
+	// Keep an unbiased estimate of the variance for inference
+	variance *= m / (m - 1)
+	n.RunningMean[j] = (1-n.Momentum)*n.RunningMean[j] + n.Momentum*mean
+	n.RunningVar[j] = (1-n.Momentum)*n.RunningVar[j] + n.Momentum*variance
+}
+
+func (n *Norm) Backward(dy *mat.Dense) *mat.Dense {
+	m, dim := dy.Dims()
+	n.Grads()
+	n.dx = reuse(n.dx, m, dim)
+
+	gradGamma, gradBeta := n.gradGamma.RawRowView(0), n.gradBeta.RawRowView(0)
+	for j := range gradGamma {
+		gradGamma[j], gradBeta[j] = 0, 0
+	}
+	for i := 0; i < m; i++ {
+		xhat := n.xhat.RawRowView(i)
+		for j, g := range dy.RawRowView(i) {
+			gradGamma[j] += g * xhat[j]
+			gradBeta[j] += g
+		}
+	}
+
+	for i := 0; i < m; i++ {
+		g, xhat, dx := dy.RawRowView(i), n.xhat.RawRowView(i), n.dx.RawRowView(i)
+
+		switch {
+		case n.Kind == LayerNorm:
+			d, sumDxhat, sumDxhatXhat := float64(dim), 0.0, 0.0
+			for j := range g {
+				sumDxhat += g[j] * n.Gamma[j]
+				sumDxhatXhat += g[j] * n.Gamma[j] * xhat[j]
+			}
+			for j := range g {
+				dx[j] = n.invStd[i] / d * (d*g[j]*n.Gamma[j] - sumDxhat - xhat[j]*sumDxhatXhat)
+			}
+		case n.batchStats:
+			// The gradients of Beta and Gamma are the sums over the mini-batch
+			// we need.
+			mf := float64(m)
+			for j := range g {
+				dx[j] = n.Gamma[j] * n.invStd[j] / mf * (mf*g[j] - gradBeta[j] - xhat[j]*gradGamma[j])
+			}
+		default:
+			for j := range g {
+				dx[j] = g[j] * n.Gamma[j] * n.invStd[j]
+			}
+		}
+	}
+	return n.dx
+}
+
+// Params returns Gamma and Beta as row vectors.
+func (n *Norm) Params() []*mat.Dense {
+	if len(n.params) == 0 || !sameStorage(n.params[0], n.Gamma) || !sameStorage(n.params[1], n.Beta) {
+		n.params = []*mat.Dense{mat.NewDense(1, len(n.Gamma), n.Gamma), mat.NewDense(1, len(n.Beta), n.Beta)}
+	}
+	return n.params
+}
+
+func (n *Norm) Grads() []*mat.Dense {
+	if n.gradGamma == nil || n.gradGamma.RawMatrix().Cols != len(n.Gamma) {
+		n.gradGamma, n.gradBeta = mat.NewDense(1, len(n.Gamma), nil), mat.NewDense(1, len(n.Beta), nil)
+		n.grads = []*mat.Dense{n.gradGamma, n.gradBeta}
+	}
+	return n.grads
+}
+
+func (n *Norm) Clone(deep bool) Layer {
+	c := Norm{Kind: n.Kind, Gamma: n.Gamma, Beta: n.Beta, RunningMean: n.RunningMean, RunningVar: n.RunningVar, Momentum: n.Momentum, Eps: n.Eps}
+	if deep {
+		c.Gamma = append([]float64(nil), n.Gamma...)
+		c.Beta = append([]float64(nil), n.Beta...)
+		if n.RunningMean != nil {
+			c.RunningMean = append([]float64(nil), n.RunningMean...)
+			c.RunningVar = append([]float64(nil), n.RunningVar...)
+		}
+	}
+	return &c
+}
+
+func (n *Norm) String() string {
+	return fmt.Sprintf("Norm(%s, %d)", n.Kind, len(n.Gamma))
+}
+
+// sameStorage checks whether m is a row vector backed by v.
+func sameStorage(m *mat.Dense, v []float64) bool {
+	data := m.RawMatrix().Data
+	return len(data) == len(v) && (len(v) == 0 || &data[0] == &v[0])
+}
+
+// grow returns a slice of length n, reusing s's storage when possible.
+func grow(s []float64, n int) []float64 {
+	if cap(s) >= n {
+		return s[:n]
+	}
+	return make([]float64, n)
+}
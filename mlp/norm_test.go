@@ -0,0 +1,183 @@
+package mlp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// randomNormMlp returns an MLP with every hidden layer normalised and random
+// parameters, running statistics included.
+func randomNormMlp(t *testing.T, dims []int, act Activation, kind NormKind) *Mlp {
+	t.Helper()
+
+	m, err := NewMlpWith(dims, act, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	rng := randomiseWeights(m, 1)
+
+	for i := 0; i < m.NHidden; i++ {
+		if err := m.SetNorm(i, kind); err != nil {
+			t.Fatalf("SetNorm() returned an error: %v", err)
+		}
+		n := m.Norms[i]
+		for j := range n.Gamma {
+			n.Gamma[j], n.Beta[j] = 1+rng.NormFloat64()/2, rng.NormFloat64()/2
+			if kind == BatchNorm {
+				n.RunningMean[j], n.RunningVar[j] = rng.NormFloat64(), 0.5+rng.Float64()
+			}
+		}
+	}
+
+	return m
+}
+
+func TestGradCheckNorm(t *testing.T) {
+	for _, kind := range []NormKind{BatchNorm, LayerNorm} {
+		for _, act := range []Activation{SigmoidAct, ReLuAct} {
+			t.Run(fmt.Sprintf("%s/%s", kind, act.Name), func(t *testing.T) {
+				m := randomNormMlp(t, []int{3, 5, 4, 2}, act, kind)
+
+				for l, relErr := range GradCheck(m, []float64{0.3, -1, 0.5}, []float64{1, 0}, 1e-6) {
+					if max := mat.Max(relErr); max > 1e-5 {
+						t.Errorf("gradient mismatch for parameter %d: max relative error %g\n%6.3g",
+							l, max, mat.Formatted(relErr, mat.FormatMATLAB()))
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestBatchGradCheckNorm(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	inputs, targets := make([][]float64, 6), make([][]float64, 6)
+	for i := range inputs {
+		inputs[i] = []float64{rng.NormFloat64(), rng.NormFloat64(), rng.NormFloat64()}
+		targets[i] = []float64{rng.Float64(), rng.Float64()}
+	}
+
+	for _, kind := range []NormKind{BatchNorm, LayerNorm} {
+		t.Run(kind.String(), func(t *testing.T) {
+			m := randomNormMlp(t, []int{3, 5, 4, 2}, SigmoidAct, kind)
+
+			m.batchGradients(inputs, targets, 1)
+
+			var analytic []*mat.Dense
+			for _, g := range m.model().Grads() {
+				analytic = append(analytic, mat.DenseCopyOf(g))
+			}
+
+			loss := func() float64 {
+				out := m.forwardBatch(m.model(), inputs, true)
+				loss := 0.0
+				for s, target := range targets {
+					loss += squaredError(out.RawRowView(s), target)
+				}
+				return loss / float64(len(inputs))
+			}
+
+			eps := 1e-6
+			for l, p := range m.params() {
+				raw := p.RawMatrix().Data
+				for k, orig := range raw {
+					raw[k] = orig + eps
+					lossPlus := loss()
+					raw[k] = orig - eps
+					lossMinus := loss()
+					raw[k] = orig
+
+					// Batch normalisation cancels the biases out, so their gradients
+					// are just noise around 0.
+					a, n := analytic[l].RawMatrix().Data[k], (lossPlus-lossMinus)/(2*eps)
+					if relErr := relativeError(a, n); relErr > 1e-5 && math.Abs(a-n) > 1e-8 {
+						t.Errorf("gradient mismatch for entry %d of parameter %d: relative error %g", k, l, relErr)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBatchNormRunningStats(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 4, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	m.SetWeights([][]float64{{1, 2, 3, -1, 0.5, 0, 2, 2, 1, -3, 1, 0.5}, {1, -1, 1, -1, 0}})
+	if err := m.SetNorm(0, BatchNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+
+	// The net activation of the first hidden neuron is x + 2y + 3
+	inputs, targets := [][]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}}, [][]float64{{0}, {1}, {1}, {0}}
+	m.AdaptBatch(inputs, targets, 0)
+
+	// mean = 4.5 and unbiased variance = 5 / 3
+	n := m.Norms[0]
+	if got, want := n.RunningMean[0], 0.1*4.5; !floatsEqual(got, want) {
+		t.Errorf("wrong running mean: %g != %g", got, want)
+	}
+	if got, want := n.RunningVar[0], 0.9+0.1*5.0/3; !floatsEqual(got, want) {
+		t.Errorf("wrong running variance: %g != %g", got, want)
+	}
+
+	// Inference relies on the running statistics alone
+	m.Training = false
+	p := m.Predictor()
+	for _, input := range inputs {
+		output, _, _ := m.ComputeActivation(input)
+		want := output[0]
+
+		got, err := p.Predict(input)
+		if err != nil {
+			t.Fatalf("Predict() returned an error: %v", err)
+		}
+		if got[0] != want {
+			t.Errorf("inference mismatch for %v: %g != %g", input, got[0], want)
+		}
+	}
+}
+
+func TestBatchNormParallelMatchesSerial(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	inputs, targets := make([][]float64, 64), make([][]float64, 64)
+	for i := range inputs {
+		inputs[i] = []float64{rng.NormFloat64(), rng.NormFloat64()}
+		targets[i] = []float64{rng.Float64()}
+	}
+
+	train := func(workers int) *Mlp {
+		m := randomNormMlp(t, []int{2, 8, 8, 1}, ReLuAct, BatchNorm)
+		tr, err := NewTrainer(16, workers, 0.1, 42)
+		if err != nil {
+			t.Fatalf("NewTrainer() returned an error: %v", err)
+		}
+		for e := 0; e < 5; e++ {
+			if err := tr.Epoch(m, inputs, targets); err != nil {
+				t.Fatalf("Epoch() returned an error: %v", err)
+			}
+		}
+		return m
+	}
+
+	serial, parallel := train(1), train(3)
+	for i, p := range serial.params() {
+		if !mat.Equal(p, parallel.params()[i]) {
+			t.Errorf("parameter %d mismatch: %6.3f != %6.3f", i,
+				mat.Formatted(parallel.params()[i], mat.FormatMATLAB()), mat.Formatted(p, mat.FormatMATLAB()))
+		}
+	}
+	for i, n := range serial.Norms {
+		for j := range n.RunningMean {
+			if n.RunningMean[j] != parallel.Norms[i].RunningMean[j] || n.RunningVar[j] != parallel.Norms[i].RunningVar[j] {
+				t.Errorf("running statistics mismatch for neuron %d of layer %d", j, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,629 @@
+package mlp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+)
+
+// ONNX data types and attribute types, as defined in onnx.proto.
+const (
+	onnxFloat  = 1
+	onnxDouble = 11
+
+	onnxAttrFloat = 1
+	onnxAttrInt   = 2
+)
+
+// The ONNX messages ExportONNX writes. They're a small subset of those defined
+// in onnx.proto, enough to describe the graph of an MLP: fields they don't know
+// about are skipped when decoding.
+type onnxModel struct {
+	IRVersion    int64
+	Opsets       []onnxOpset
+	ProducerName string
+	Graph        onnxGraph
+}
+
+type onnxOpset struct {
+	Domain  string
+	Version int64
+}
+
+type onnxGraph struct {
+	Name         string
+	Nodes        []onnxNode
+	Initializers []onnxTensor
+	Inputs       []onnxValueInfo
+	Outputs      []onnxValueInfo
+}
+
+type onnxNode struct {
+	Name, OpType, Domain string
+	Inputs, Outputs      []string
+	Attrs                []onnxAttr
+}
+
+type onnxAttr struct {
+	Name string
+	Type int64
+	F    float32
+	I    int64
+}
+
+// onnxTensor holds its data as float64 whatever its type: decoding converts
+// FLOAT and DOUBLE tensors alike.
+type onnxTensor struct {
+	Name     string
+	Dims     []int64
+	DataType int64
+	Data     []float64
+}
+
+// onnxValueInfo describes a tensor taken or produced by a graph. Dimensions
+// with a Param are symbolic, such as the number of samples.
+type onnxValueInfo struct {
+	Name     string
+	ElemType int64
+	Dims     []onnxDim
+}
+
+type onnxDim struct {
+	Value int64
+	Param string
+}
+
+// ONNXOptions tweak the graph ExportONNX writes.
+type ONNXOptions struct {
+	// MatMul maps each layer's inputs through a MatMul and an Add node
+	// instead of a single Gemm one.
+	MatMul bool
+
+	// Softmax turns the outputs into class probabilities.
+	Softmax bool
+
+	// Float32 stores the parameters and takes the inputs as 32-bit floats,
+	// which more runtimes support, rather than as 64-bit ones.
+	Float32 bool
+}
+
+// ExportONNX writes the MLP into the given file as an ONNX model mapping an
+// input tensor of shape [batch, InDim] to an output one of shape
+// [batch, OutDim]. The model behaves just like the MLP does outside of
+// training: batch normalisation relies on its running estimates and there's
+// no dropout.
+func (mlp *Mlp) ExportONNX(fpath string, opts ONNXOptions) error {
+	data, err := mlp.MarshalONNX(opts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fpath, data, 0644)
+}
+
+// MarshalONNX encodes the MLP as an ONNX model: check ExportONNX.
+func (mlp *Mlp) MarshalONNX(opts ONNXOptions) ([]byte, error) {
+	m, err := mlp.onnxModel(opts)
+	if err != nil {
+		return nil, err
+	}
+	return m.marshal(nil), nil
+}
+
+func (mlp *Mlp) onnxModel(opts ONNXOptions) (*onnxModel, error) {
+	if _, ok := Activations[mlp.ActFunc.Name]; !ok {
+		return nil, fmt.Errorf("can't export custom activation function %q to ONNX", mlp.ActFunc.Name)
+	}
+
+	elemType := int64(onnxDouble)
+	if opts.Float32 {
+		elemType = onnxFloat
+	}
+	g := onnxGraph{
+		Name:    "mlp",
+		Inputs:  []onnxValueInfo{{Name: "input", ElemType: elemType, Dims: []onnxDim{{Param: "batch"}, {Value: int64(mlp.InDim)}}}},
+		Outputs: []onnxValueInfo{{Name: "output", ElemType: elemType, Dims: []onnxDim{{Param: "batch"}, {Value: int64(mlp.OutDim)}}}},
+	}
+
+	// node appends a node whose output is named after it and returns it so
+	// that the next one can take it.
+	node := func(name, op string, inputs []string, attrs ...onnxAttr) string {
+		g.Nodes = append(g.Nodes, onnxNode{Name: name, OpType: op, Inputs: inputs, Outputs: []string{name}, Attrs: attrs})
+		return name
+	}
+	tensor := func(name string, data []float64, dims ...int64) string {
+		g.Initializers = append(g.Initializers, onnxTensor{Name: name, Dims: dims, DataType: elemType, Data: data})
+		return name
+	}
+
+	// LayerNormalization was only added in opset 17
+	opset := int64(13)
+	x := "input"
+	for i, w := range mlp.Weights {
+		out, in := w.Dims()
+		in--
+
+		weights, bias := make([]float64, 0, out*in), make([]float64, out)
+		for j := 0; j < out; j++ {
+			row := w.RawRowView(j)
+			weights, bias[j] = append(weights, row[:in]...), row[in]
+		}
+
+		prefix := fmt.Sprintf("dense%d", i)
+		if opts.MatMul {
+			// MatMul takes the weights as an in x out matrix
+			wt := make([]float64, 0, in*out)
+			for k := 0; k < in; k++ {
+				for j := 0; j < out; j++ {
+					wt = append(wt, weights[j*in+k])
+				}
+			}
+			x = node(prefix+".matmul", "MatMul", []string{x, tensor(prefix+".weight", wt, int64(in), int64(out))})
+			x = node(prefix+".add", "Add", []string{x, tensor(prefix+".bias", bias, int64(out))})
+		} else {
+			x = node(prefix, "Gemm", []string{x, tensor(prefix+".weight", weights, int64(out), int64(in)), tensor(prefix+".bias", bias, int64(out))},
+				onnxAttr{Name: "transB", Type: onnxAttrInt, I: 1})
+		}
+
+		if n := mlp.norm(i); n != nil {
+			prefix := fmt.Sprintf("norm%d", i)
+			eps := onnxAttr{Name: "epsilon", Type: onnxAttrFloat, F: float32(n.Eps)}
+			scale, shift := tensor(prefix+".gamma", n.Gamma, int64(out)), tensor(prefix+".beta", n.Beta, int64(out))
+			switch n.Kind {
+			case BatchNorm:
+				x = node(prefix, "BatchNormalization", []string{x, scale, shift,
+					tensor(prefix+".running_mean", n.RunningMean, int64(out)), tensor(prefix+".running_var", n.RunningVar, int64(out))}, eps)
+			case LayerNorm:
+				x = node(prefix, "LayerNormalization", []string{x, scale, shift}, eps, onnxAttr{Name: "axis", Type: onnxAttrInt, I: -1})
+				opset = 17
+			}
+		}
+
+		prefix = fmt.Sprintf("act%d", i)
+		switch mlp.ActFunc.Name {
+		case SigmoidAct.Name:
+			x = node(prefix, "Sigmoid", []string{x})
+		case ReLuAct.Name:
+			x = node(prefix, "Relu", []string{x})
+		case UnitStepAct.Name:
+			// There's no unit step operator, but Relu(Sign(x)) is 1 for
+			// positive inputs and 0 otherwise
+			x = node(prefix, "Relu", []string{node(prefix+".sign", "Sign", []string{x})})
+		}
+	}
+	if opts.Softmax {
+		node("softmax", "Softmax", []string{x}, onnxAttr{Name: "axis", Type: onnxAttrInt, I: -1})
+	}
+	g.Nodes[len(g.Nodes)-1].Outputs[0] = "output"
+
+	// Opset 17 comes with IR version 8 and every earlier one down to 13
+	// with IR version 7
+	ir := int64(7)
+	if opset >= 17 {
+		ir = 8
+	}
+	return &onnxModel{IRVersion: ir, Opsets: []onnxOpset{{Version: opset}}, ProducerName: "mlp-go", Graph: g}, nil
+}
+
+// ONNX models are protocol buffers, encoded by hand to keep the mlp package
+// free of google.golang.org/protobuf.
+
+// Protocol buffer wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendVarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+func appendFixed32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendFixed64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendTag(b []byte, num, wire int) []byte {
+	return appendVarint(b, uint64(num)<<3|uint64(wire))
+}
+
+func appendInt(b []byte, num int, v int64) []byte {
+	return appendVarint(appendTag(b, num, wireVarint), uint64(v))
+}
+
+func appendBytes(b []byte, num int, data []byte) []byte {
+	return append(appendVarint(appendTag(b, num, wireBytes), uint64(len(data))), data...)
+}
+
+func appendString(b []byte, num int, s string) []byte {
+	return appendBytes(b, num, []byte(s))
+}
+
+func (m *onnxModel) marshal(b []byte) []byte {
+	b = appendInt(b, 1, m.IRVersion)
+	b = appendString(b, 2, m.ProducerName)
+	b = appendBytes(b, 7, m.Graph.marshal(nil))
+	for _, o := range m.Opsets {
+		b = appendBytes(b, 8, appendInt(appendString(nil, 1, o.Domain), 2, o.Version))
+	}
+	return b
+}
+
+func (g *onnxGraph) marshal(b []byte) []byte {
+	for _, n := range g.Nodes {
+		b = appendBytes(b, 1, n.marshal(nil))
+	}
+	b = appendString(b, 2, g.Name)
+	for _, t := range g.Initializers {
+		b = appendBytes(b, 5, t.marshal(nil))
+	}
+	for _, v := range g.Inputs {
+		b = appendBytes(b, 11, v.marshal(nil))
+	}
+	for _, v := range g.Outputs {
+		b = appendBytes(b, 12, v.marshal(nil))
+	}
+	return b
+}
+
+func (n *onnxNode) marshal(b []byte) []byte {
+	for _, in := range n.Inputs {
+		b = appendString(b, 1, in)
+	}
+	for _, out := range n.Outputs {
+		b = appendString(b, 2, out)
+	}
+	b = appendString(b, 3, n.Name)
+	b = appendString(b, 4, n.OpType)
+	for _, a := range n.Attrs {
+		b = appendBytes(b, 5, a.marshal(nil))
+	}
+	if n.Domain != "" {
+		b = appendString(b, 7, n.Domain)
+	}
+	return b
+}
+
+func (a *onnxAttr) marshal(b []byte) []byte {
+	b = appendString(b, 1, a.Name)
+	switch a.Type {
+	case onnxAttrFloat:
+		b = appendFixed32(appendTag(b, 2, wireFixed32), math.Float32bits(a.F))
+	case onnxAttrInt:
+		b = appendInt(b, 3, a.I)
+	}
+	return appendInt(b, 20, a.Type)
+}
+
+// marshal stores the data of the tensor as raw little-endian bytes, as most
+// exporters do.
+func (t *onnxTensor) marshal(b []byte) []byte {
+	for _, d := range t.Dims {
+		b = appendInt(b, 1, d)
+	}
+	b = appendInt(b, 2, t.DataType)
+	b = appendString(b, 8, t.Name)
+
+	var raw []byte
+	for _, v := range t.Data {
+		if t.DataType == onnxFloat {
+			raw = appendFixed32(raw, math.Float32bits(float32(v)))
+		} else {
+			raw = appendFixed64(raw, math.Float64bits(v))
+		}
+	}
+	return appendBytes(b, 9, raw)
+}
+
+func (v *onnxValueInfo) marshal(b []byte) []byte {
+	var shape []byte
+	for _, d := range v.Dims {
+		if d.Param != "" {
+			shape = appendBytes(shape, 1, appendString(nil, 2, d.Param))
+		} else {
+			shape = appendBytes(shape, 1, appendInt(nil, 1, d.Value))
+		}
+	}
+	tensorType := appendBytes(appendInt(nil, 1, v.ElemType), 2, shape)
+
+	b = appendString(b, 1, v.Name)
+	return appendBytes(b, 2, appendBytes(nil, 1, tensorType))
+}
+
+// protoField is a field of an encoded protocol buffer message. Varint and
+// fixed-size fields hold their value in v and length-delimited ones hold
+// their bytes in data.
+type protoField struct {
+	num, wire int
+	v         uint64
+	data      []byte
+}
+
+func consumeVarint(b []byte) (uint64, int, error) {
+	v, n := binary.Uvarint(b)
+	if n <= 0 {
+		return 0, 0, fmt.Errorf("malformed varint")
+	}
+	return v, n, nil
+}
+
+// parseProto calls fn with each field of the encoded message in b.
+func parseProto(b []byte, fn func(f protoField) error) error {
+	for len(b) > 0 {
+		tag, n, err := consumeVarint(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+
+		f := protoField{num: int(tag >> 3), wire: int(tag & 7)}
+		switch f.wire {
+		case wireVarint:
+			if f.v, n, err = consumeVarint(b); err != nil {
+				return err
+			}
+		case wireFixed64:
+			if n = 8; len(b) < n {
+				return fmt.Errorf("truncated field %d", f.num)
+			}
+			f.v = binary.LittleEndian.Uint64(b)
+		case wireFixed32:
+			if n = 4; len(b) < n {
+				return fmt.Errorf("truncated field %d", f.num)
+			}
+			f.v = uint64(binary.LittleEndian.Uint32(b))
+		case wireBytes:
+			size, m, err := consumeVarint(b)
+			if err != nil {
+				return err
+			}
+			if uint64(len(b)-m) < size {
+				return fmt.Errorf("truncated field %d", f.num)
+			}
+			f.data, n = b[m:m+int(size)], m+int(size)
+		default:
+			return fmt.Errorf("unsupported wire type %d for field %d", f.wire, f.num)
+		}
+		b = b[n:]
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ints decodes a repeated integer field, be it packed or not.
+func (f protoField) ints() ([]int64, error) {
+	if f.wire != wireBytes {
+		return []int64{int64(f.v)}, nil
+	}
+	var vs []int64
+	for b := f.data; len(b) > 0; {
+		v, n, err := consumeVarint(b)
+		if err != nil {
+			return nil, err
+		}
+		vs, b = append(vs, int64(v)), b[n:]
+	}
+	return vs, nil
+}
+
+// floats decodes a repeated float or double field, be it packed or not.
+func (f protoField) floats(double bool) ([]float64, error) {
+	if f.wire != wireBytes {
+		if double {
+			return []float64{math.Float64frombits(f.v)}, nil
+		}
+		return []float64{float64(math.Float32frombits(uint32(f.v)))}, nil
+	}
+	return decodeFloats(f.data, double)
+}
+
+// decodeFloats decodes little-endian floats or doubles.
+func decodeFloats(b []byte, double bool) ([]float64, error) {
+	size := 4
+	if double {
+		size = 8
+	}
+	if len(b)%size != 0 {
+		return nil, fmt.Errorf("%d bytes don't make up a whole number of %d-byte values", len(b), size)
+	}
+	vs := make([]float64, 0, len(b)/size)
+	for ; len(b) > 0; b = b[size:] {
+		if double {
+			vs = append(vs, math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		} else {
+			vs = append(vs, float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+		}
+	}
+	return vs, nil
+}
+
+func (m *onnxModel) unmarshal(b []byte) error {
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.IRVersion = int64(f.v)
+		case 2:
+			m.ProducerName = string(f.data)
+		case 7:
+			return m.Graph.unmarshal(f.data)
+		case 8:
+			var o onnxOpset
+			err := parseProto(f.data, func(f protoField) error {
+				switch f.num {
+				case 1:
+					o.Domain = string(f.data)
+				case 2:
+					o.Version = int64(f.v)
+				}
+				return nil
+			})
+			m.Opsets = append(m.Opsets, o)
+			return err
+		}
+		return nil
+	})
+}
+
+func (g *onnxGraph) unmarshal(b []byte) error {
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			var n onnxNode
+			g.Nodes = append(g.Nodes, n)
+			return g.Nodes[len(g.Nodes)-1].unmarshal(f.data)
+		case 2:
+			g.Name = string(f.data)
+		case 5:
+			var t onnxTensor
+			g.Initializers = append(g.Initializers, t)
+			return g.Initializers[len(g.Initializers)-1].unmarshal(f.data)
+		case 11, 12:
+			var v onnxValueInfo
+			if err := v.unmarshal(f.data); err != nil {
+				return err
+			}
+			if f.num == 11 {
+				g.Inputs = append(g.Inputs, v)
+			} else {
+				g.Outputs = append(g.Outputs, v)
+			}
+		}
+		return nil
+	})
+}
+
+func (n *onnxNode) unmarshal(b []byte) error {
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			n.Inputs = append(n.Inputs, string(f.data))
+		case 2:
+			n.Outputs = append(n.Outputs, string(f.data))
+		case 3:
+			n.Name = string(f.data)
+		case 4:
+			n.OpType = string(f.data)
+		case 5:
+			var a onnxAttr
+			if err := a.unmarshal(f.data); err != nil {
+				return err
+			}
+			n.Attrs = append(n.Attrs, a)
+		case 7:
+			n.Domain = string(f.data)
+		}
+		return nil
+	})
+}
+
+func (a *onnxAttr) unmarshal(b []byte) error {
+	return parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			a.Name = string(f.data)
+		case 2:
+			a.F = math.Float32frombits(uint32(f.v))
+		case 3:
+			a.I = int64(f.v)
+		case 20:
+			a.Type = int64(f.v)
+		}
+		return nil
+	})
+}
+
+func (t *onnxTensor) unmarshal(b []byte) error {
+	var raw []byte
+	err := parseProto(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			dims, err := f.ints()
+			t.Dims = append(t.Dims, dims...)
+			return err
+		case 2:
+			t.DataType = int64(f.v)
+		case 4, 10:
+			data, err := f.floats(f.num == 10)
+			t.Data = append(t.Data, data...)
+			return err
+		case 8:
+			t.Name = string(f.data)
+		case 9:
+			raw = f.data
+		case 13:
+			return fmt.Errorf("tensor %s is stored externally", t.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if t.DataType != onnxFloat && t.DataType != onnxDouble {
+		return fmt.Errorf("tensor %s holds data of type %d, but only FLOAT and DOUBLE are supported", t.Name, t.DataType)
+	}
+	if raw != nil {
+		t.Data, err = decodeFloats(raw, t.DataType == onnxDouble)
+	}
+	return err
+}
+
+func (v *onnxValueInfo) unmarshal(b []byte) error {
+	return parseProto(b, func(f protoField) error {
+		if f.num == 1 {
+			v.Name = string(f.data)
+			return nil
+		}
+		if f.num != 2 {
+			return nil
+		}
+		// TypeProto holds a TypeProto.Tensor in field 1, which holds the
+		// element type and the shape
+		return parseProto(f.data, func(f protoField) error {
+			if f.num != 1 {
+				return nil
+			}
+			return parseProto(f.data, func(f protoField) error {
+				switch f.num {
+				case 1:
+					v.ElemType = int64(f.v)
+				case 2:
+					return parseProto(f.data, func(f protoField) error {
+						if f.num != 1 {
+							return nil
+						}
+						var d onnxDim
+						err := parseProto(f.data, func(f protoField) error {
+							switch f.num {
+							case 1:
+								d.Value = int64(f.v)
+							case 2:
+								d.Param = string(f.data)
+							}
+							return nil
+						})
+						v.Dims = append(v.Dims, d)
+						return err
+					})
+				}
+				return nil
+			})
+		})
+	})
+}
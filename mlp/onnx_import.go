@@ -0,0 +1,402 @@
+package mlp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// onnxOps lists the operators ImportONNX understands.
+var onnxOps = []string{"Gemm", "MatMul", "Add", "BatchNormalization", "LayerNormalization", "Sigmoid", "Relu", "Sign", "Softmax", "Identity", "Dropout"}
+
+// onnxLayer gathers the nodes making up a layer of the MLP being imported.
+type onnxLayer struct {
+	weights []float64
+	in, out int
+	bias    []float64
+	norm    *Norm
+
+	// act is the name of the activation function, being "sign" after a
+	// Sign node waiting for the Relu that makes up the unit step.
+	act string
+}
+
+// ImportONNX reads an ONNX model mapping a single input to a single output
+// through a chain of layers, each made up of either a Gemm node or a MatMul
+// one followed by an optional Add, an optional BatchNormalization or
+// LayerNormalization for hidden layers and an activation function. Every
+// layer must apply the same one: Sigmoid, Relu or Relu(Sign(x)), which is the
+// unit step. Models exported with ExportONNX are fine.
+//
+// Identity and Dropout nodes are skipped, as they do nothing outside of
+// training, and so is a Softmax at the end: the imported MLP produces the
+// outputs before it, of which ClassProbabilities takes the softmax.
+func ImportONNX(fpath string) (*Mlp, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalONNX(data)
+}
+
+// UnmarshalONNX rebuilds an MLP from an encoded ONNX model: check ImportONNX.
+func UnmarshalONNX(data []byte) (*Mlp, error) {
+	var model onnxModel
+	if err := model.unmarshal(data); err != nil {
+		return nil, fmt.Errorf("couldn't decode the ONNX model: %v", err)
+	}
+	return model.mlp()
+}
+
+func (m *onnxModel) mlp() (*Mlp, error) {
+	g := &m.Graph
+	tensors := map[string]*onnxTensor{}
+	for i := range g.Initializers {
+		tensors[g.Initializers[i].Name] = &g.Initializers[i]
+	}
+
+	// Older models list the initializers among the inputs too
+	var inputs []onnxValueInfo
+	for _, in := range g.Inputs {
+		if _, ok := tensors[in.Name]; !ok {
+			inputs = append(inputs, in)
+		}
+	}
+	if len(inputs) != 1 || len(g.Outputs) != 1 {
+		return nil, fmt.Errorf("the graph should have a single input and output, but it has %d and %d", len(inputs), len(g.Outputs))
+	}
+
+	consumers := map[string][]*onnxNode{}
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		if n.Domain != "" && n.Domain != "ai.onnx" {
+			return nil, fmt.Errorf("node %s belongs to the unsupported domain %s", n.Name, n.Domain)
+		}
+		for _, in := range n.Inputs {
+			if _, ok := tensors[in]; !ok && in != "" {
+				consumers[in] = append(consumers[in], n)
+			}
+		}
+	}
+
+	var layers []*onnxLayer
+	visited := map[*onnxNode]bool{}
+	x, softmax := inputs[0].Name, false
+	for steps := 0; x != g.Outputs[0].Name; steps++ {
+		next := consumers[x]
+		switch {
+		case steps == len(g.Nodes):
+			return nil, fmt.Errorf("the graph loops back on itself")
+		case len(next) == 0:
+			return nil, fmt.Errorf("nothing takes %s, so it never reaches the output %s", x, g.Outputs[0].Name)
+		case len(next) > 1:
+			return nil, fmt.Errorf("the graph branches at %s: only chains of layers are supported", x)
+		case softmax:
+			return nil, fmt.Errorf("node %s follows the Softmax, which must come last", next[0].Name)
+		}
+		n := next[0]
+		visited[n] = true
+		if len(n.Outputs) == 0 {
+			return nil, fmt.Errorf("node %s has no outputs", n.Name)
+		}
+
+		var layer *onnxLayer
+		if len(layers) > 0 {
+			layer = layers[len(layers)-1]
+		}
+		var err error
+		switch n.OpType {
+		case "Gemm", "MatMul":
+			if layer != nil && (layer.act == "" || layer.act == "sign") {
+				return nil, fmt.Errorf("node %s starts a new layer, but layer %d applies no activation function", n.Name, len(layers)-1)
+			}
+			layer = &onnxLayer{}
+			layers = append(layers, layer)
+			if n.OpType == "Gemm" {
+				err = layer.gemm(n, tensors)
+			} else {
+				err = layer.matMul(n, tensors)
+			}
+		case "Add":
+			err = layer.add(n, x, tensors)
+		case "BatchNormalization", "LayerNormalization":
+			err = layer.normalise(n, tensors)
+		case "Sigmoid", "Relu", "Sign":
+			err = layer.activate(n)
+		case "Softmax":
+			if layer == nil || layer.act == "" || layer.act == "sign" {
+				return nil, fmt.Errorf("Softmax node %s should follow an activation function", n.Name)
+			}
+			if a, ok := n.attr("axis"); ok && a.I != -1 && a.I != 1 {
+				return nil, fmt.Errorf("Softmax node %s normalises over axis %d rather than over the outputs", n.Name, a.I)
+			}
+			softmax = true
+		case "Identity", "Dropout":
+		default:
+			return nil, fmt.Errorf("unsupported operator %s in node %s: only %s are", n.OpType, n.Name, strings.Join(onnxOps, ", "))
+		}
+		if err != nil {
+			return nil, err
+		}
+		x = n.Outputs[0]
+	}
+
+	// Nodes branching off the chain would otherwise be dropped silently
+	for i := range g.Nodes {
+		if n := &g.Nodes[i]; !visited[n] {
+			return nil, fmt.Errorf("node %s doesn't lie on the path from %s to %s: only chains of layers are supported", n.Name, inputs[0].Name, g.Outputs[0].Name)
+		}
+	}
+
+	return buildOnnxMlp(layers, inputs[0])
+}
+
+// buildOnnxMlp checks the layers fit together as an MLP and builds it.
+func buildOnnxMlp(layers []*onnxLayer, input onnxValueInfo) (*Mlp, error) {
+	if len(layers) < 2 {
+		return nil, fmt.Errorf("the graph has %d layers, but an MLP needs at least a hidden and an output one", len(layers))
+	}
+
+	dims := []int{layers[0].in}
+	if len(input.Dims) == 2 && input.Dims[1].Value > 0 && int(input.Dims[1].Value) != layers[0].in {
+		return nil, fmt.Errorf("input %s has %d features, but the first layer takes %d", input.Name, input.Dims[1].Value, layers[0].in)
+	}
+	for i, l := range layers {
+		switch {
+		case l.act == "" || l.act == "sign":
+			return nil, fmt.Errorf("layer %d applies no activation function", i)
+		case l.act != layers[0].act:
+			return nil, fmt.Errorf("layer %d applies %s, but an MLP applies the same activation function as the first layer, %s, everywhere", i, l.act, layers[0].act)
+		case i > 0 && l.in != layers[i-1].out:
+			return nil, fmt.Errorf("layer %d takes %d inputs, but layer %d produces %d outputs", i, l.in, i-1, layers[i-1].out)
+		case i == len(layers)-1 && l.norm != nil:
+			return nil, fmt.Errorf("the output layer is normalised, but MLPs only normalise hidden layers")
+		}
+		dims = append(dims, l.out)
+	}
+
+	mlp, err := NewMlpWith(dims, Activations[layers[0].act], 1)
+	if err != nil {
+		return nil, err
+	}
+	for i, l := range layers {
+		w := mlp.Weights[i]
+		for j := 0; j < l.out; j++ {
+			row := w.RawRowView(j)
+			copy(row, l.weights[j*l.in:(j+1)*l.in])
+			if l.bias != nil {
+				row[l.in] = l.bias[j]
+			} else {
+				row[l.in] = 0
+			}
+		}
+		if l.norm != nil {
+			for len(mlp.Norms) <= i {
+				mlp.Norms = append(mlp.Norms, nil)
+			}
+			mlp.Norms[i] = l.norm
+		}
+	}
+	return mlp, nil
+}
+
+// initializer returns the initializer with the given name, checking it holds
+// as many values as its dimensions call for.
+func initializer(n *onnxNode, name string, tensors map[string]*onnxTensor) (*onnxTensor, error) {
+	t, ok := tensors[name]
+	if !ok {
+		return nil, fmt.Errorf("node %s takes %s, which isn't an initializer", n.Name, name)
+	}
+	size := int64(1)
+	for _, d := range t.Dims {
+		if d < 0 {
+			return nil, fmt.Errorf("tensor %s has the negative dimensions %v", t.Name, t.Dims)
+		}
+		size *= d
+	}
+	if size != int64(len(t.Data)) {
+		return nil, fmt.Errorf("tensor %s of shape %v holds %d values", t.Name, t.Dims, len(t.Data))
+	}
+	return t, nil
+}
+
+// vector returns the values of the initializer, checking it holds one for
+// each of the dim neurons of a layer, possibly as a 1 x dim matrix.
+func vector(n *onnxNode, name string, dim int, tensors map[string]*onnxTensor) ([]float64, error) {
+	t, err := initializer(n, name, tensors)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.Data) != dim || len(t.Dims) > 2 || (len(t.Dims) == 2 && t.Dims[0] != 1) {
+		return nil, fmt.Errorf("node %s takes %s of shape %v, but its layer has %d neurons", n.Name, name, t.Dims, dim)
+	}
+	return t.Data, nil
+}
+
+// attr returns the attribute of the node with the given name, if any.
+func (n *onnxNode) attr(name string) (onnxAttr, bool) {
+	for _, a := range n.Attrs {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return onnxAttr{}, false
+}
+
+// gemm takes the weights and biases of a Gemm node computing
+// alpha * x * B + beta * C, where B may be transposed.
+func (l *onnxLayer) gemm(n *onnxNode, tensors map[string]*onnxTensor) error {
+	if len(n.Inputs) < 2 {
+		return fmt.Errorf("Gemm node %s should take at least 2 inputs", n.Name)
+	}
+	if a, ok := n.attr("transA"); ok && a.I != 0 {
+		return fmt.Errorf("Gemm node %s transposes its input, which isn't supported", n.Name)
+	}
+	alpha, beta := 1.0, 1.0
+	if a, ok := n.attr("alpha"); ok {
+		alpha = float64(a.F)
+	}
+	if a, ok := n.attr("beta"); ok {
+		beta = float64(a.F)
+	}
+	transB, _ := n.attr("transB")
+
+	if err := l.setWeights(n, n.Inputs[1], transB.I == 0, alpha, tensors); err != nil {
+		return err
+	}
+	if len(n.Inputs) < 3 || n.Inputs[2] == "" {
+		return nil
+	}
+	bias, err := vector(n, n.Inputs[2], l.out, tensors)
+	if err != nil {
+		return err
+	}
+	l.bias = make([]float64, l.out)
+	for j, b := range bias {
+		l.bias[j] = beta * b
+	}
+	return nil
+}
+
+// matMul takes the weights of a MatMul node, which holds them as an
+// in x out matrix.
+func (l *onnxLayer) matMul(n *onnxNode, tensors map[string]*onnxTensor) error {
+	if len(n.Inputs) != 2 {
+		return fmt.Errorf("MatMul node %s should take 2 inputs", n.Name)
+	}
+	return l.setWeights(n, n.Inputs[1], true, 1, tensors)
+}
+
+// setWeights stores the weights in the given initializer scaled by alpha as an
+// out x in matrix, transposing them if needed.
+func (l *onnxLayer) setWeights(n *onnxNode, name string, transpose bool, alpha float64, tensors map[string]*onnxTensor) error {
+	t, err := initializer(n, name, tensors)
+	if err != nil {
+		return err
+	}
+	if len(t.Dims) != 2 {
+		return fmt.Errorf("node %s takes the weights %s of shape %v, but they should make up a matrix", n.Name, name, t.Dims)
+	}
+
+	l.out, l.in = int(t.Dims[0]), int(t.Dims[1])
+	if transpose {
+		l.out, l.in = l.in, l.out
+	}
+	if l.out <= 0 || l.in <= 0 {
+		return fmt.Errorf("node %s takes the weights %s of shape %v, but every layer needs at least an input and a neuron", n.Name, name, t.Dims)
+	}
+	l.weights = make([]float64, l.out*l.in)
+	for j := 0; j < l.out; j++ {
+		for k := 0; k < l.in; k++ {
+			v := t.Data[j*l.in+k]
+			if transpose {
+				v = t.Data[k*l.out+j]
+			}
+			l.weights[j*l.in+k] = alpha * v
+		}
+	}
+	return nil
+}
+
+// add takes the biases of an Add node following a MatMul one, which may take
+// the output of the MatMul either first or second.
+func (l *onnxLayer) add(n *onnxNode, x string, tensors map[string]*onnxTensor) error {
+	if l == nil || l.bias != nil || l.norm != nil || l.act != "" {
+		return fmt.Errorf("Add node %s should follow a MatMul one", n.Name)
+	}
+	if len(n.Inputs) != 2 {
+		return fmt.Errorf("Add node %s should take 2 inputs", n.Name)
+	}
+	name := n.Inputs[0]
+	if name == x {
+		name = n.Inputs[1]
+	}
+	bias, err := vector(n, name, l.out, tensors)
+	if err != nil {
+		return err
+	}
+	l.bias = append([]float64(nil), bias...)
+	return nil
+}
+
+// normalise takes the parameters of a BatchNormalization or a
+// LayerNormalization node.
+func (l *onnxLayer) normalise(n *onnxNode, tensors map[string]*onnxTensor) error {
+	if l == nil || l.norm != nil || l.act != "" {
+		return fmt.Errorf("%s node %s should follow a Gemm, MatMul or Add one", n.OpType, n.Name)
+	}
+
+	kind, inputs := BatchNorm, 5
+	if n.OpType == "LayerNormalization" {
+		kind, inputs = LayerNorm, 2
+		if a, ok := n.attr("axis"); ok && a.I != -1 && a.I != 1 {
+			return fmt.Errorf("LayerNormalization node %s normalises over axis %d rather than over the neurons", n.Name, a.I)
+		}
+	}
+	if len(n.Inputs) < inputs {
+		return fmt.Errorf("%s node %s should take at least %d inputs", n.OpType, n.Name, inputs)
+	}
+
+	norm := NewNorm(kind, l.out)
+	if a, ok := n.attr("epsilon"); ok {
+		norm.Eps = float64(a.F)
+	}
+	if !(norm.Eps > 0) {
+		return fmt.Errorf("%s node %s has a non-positive epsilon %g", n.OpType, n.Name, norm.Eps)
+	}
+
+	// The inputs following the normalised one hold Gamma, Beta and, for
+	// batch normalisation, the running mean and variance
+	params := [][]float64{norm.Gamma, norm.Beta, norm.RunningMean, norm.RunningVar}
+	for i, name := range n.Inputs[1:] {
+		if name == "" || i >= len(params) || params[i] == nil {
+			continue
+		}
+		v, err := vector(n, name, l.out, tensors)
+		if err != nil {
+			return err
+		}
+		copy(params[i], v)
+	}
+	l.norm = norm
+	return nil
+}
+
+// activate takes the activation function of the layer, which is the unit step
+// for a Sign node followed by a Relu one.
+func (l *onnxLayer) activate(n *onnxNode) error {
+	if l == nil || (l.act != "" && !(l.act == "sign" && n.OpType == "Relu")) {
+		return fmt.Errorf("%s node %s should follow a Gemm, MatMul, Add or normalisation one", n.OpType, n.Name)
+	}
+	switch {
+	case n.OpType == "Sign":
+		l.act = "sign"
+	case l.act == "sign":
+		l.act = UnitStepAct.Name
+	case n.OpType == "Sigmoid":
+		l.act = SigmoidAct.Name
+	default:
+		l.act = ReLuAct.Name
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package mlp
+
+import (
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// samePredictions checks both MLPs map a few inputs to the same outputs
+// within tol.
+func samePredictions(t *testing.T, want, got *Mlp, tol float64) {
+	if got.InDim != want.InDim || got.OutDim != want.OutDim || !reflect.DeepEqual(got.HiddenDim, want.HiddenDim) {
+		t.Fatalf("wrong dimensions: %d / %v / %d, expected %d / %v / %d",
+			got.InDim, got.HiddenDim, got.OutDim, want.InDim, want.HiddenDim, want.OutDim)
+	}
+	if got.ActFunc.Name != want.ActFunc.Name {
+		t.Errorf("wrong activation function: %s, expected %s", got.ActFunc.Name, want.ActFunc.Name)
+	}
+
+	inputs := [][]float64{make([]float64, want.InDim), make([]float64, want.InDim), make([]float64, want.InDim)}
+	for i := range inputs {
+		for j := range inputs[i] {
+			inputs[i][j] = float64((i+1)*(j+2)%7) - 3.2
+		}
+	}
+	wantOut, _ := want.Predictor().PredictBatch(inputs)
+	gotOut, err := got.Predictor().PredictBatch(inputs)
+	if err != nil {
+		t.Fatalf("PredictBatch() returned an error: %v", err)
+	}
+	for i := range inputs {
+		for j := range wantOut[i] {
+			if math.Abs(gotOut[i][j]-wantOut[i][j]) > tol {
+				t.Errorf("wrong output %d for input %d: %v, expected %v", j, i, gotOut[i][j], wantOut[i][j])
+			}
+		}
+	}
+}
+
+func TestImportONNXRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		act   Activation
+		norms []NormKind
+		opts  ONNXOptions
+		tol   float64
+	}{
+		{"gemm", SigmoidAct, nil, ONNXOptions{}, 0},
+		{"matmul", ReLuAct, nil, ONNXOptions{MatMul: true}, 0},
+		{"softmax", SigmoidAct, nil, ONNXOptions{Softmax: true}, 0},
+		{"float32", ReLuAct, nil, ONNXOptions{Float32: true}, 1e-5},
+		{"norms", UnitStepAct, []NormKind{BatchNorm, LayerNorm}, ONNXOptions{}, 0},
+		{"norms_matmul", SigmoidAct, []NormKind{LayerNorm, BatchNorm}, ONNXOptions{MatMul: true}, 1e-6},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewMlpWith([]int{3, 5, 4, 2}, tc.act, 1)
+			if err != nil {
+				t.Fatalf("NewMlpWith() returned an error: %v", err)
+			}
+			for i, kind := range tc.norms {
+				if err := m.SetNorm(i, kind); err != nil {
+					t.Fatalf("SetNorm() returned an error: %v", err)
+				}
+				m.Norms[i].Gamma[1], m.Norms[i].Beta[2] = 1.5, -0.5
+				if kind == BatchNorm {
+					m.Norms[i].RunningMean[0], m.Norms[i].RunningVar[3] = 0.3, 2
+				}
+			}
+
+			fpath := filepath.Join(t.TempDir(), "mlp.onnx")
+			if err := m.ExportONNX(fpath, tc.opts); err != nil {
+				t.Fatalf("ExportONNX() returned an error: %v", err)
+			}
+			imported, err := ImportONNX(fpath)
+			if err != nil {
+				t.Fatalf("ImportONNX() returned an error: %v", err)
+			}
+			samePredictions(t, m, imported, tc.tol)
+		})
+	}
+}
+
+// onnxChain returns a model whose graph runs the nodes one after the other,
+// each taking the output of the previous one followed by the given
+// initializers.
+func onnxChain(inDim int64, tensors []onnxTensor, nodes ...onnxNode) *onnxModel {
+	x := "input"
+	for i := range nodes {
+		nodes[i].Inputs = append([]string{x}, nodes[i].Inputs...)
+		x = nodes[i].Name
+		if i == len(nodes)-1 {
+			x = "output"
+		}
+		nodes[i].Outputs = []string{x}
+	}
+	return &onnxModel{IRVersion: 7, Opsets: []onnxOpset{{Version: 13}}, Graph: onnxGraph{
+		Nodes: nodes, Initializers: tensors,
+		Inputs:  []onnxValueInfo{{Name: "input", ElemType: onnxFloat, Dims: []onnxDim{{Param: "N"}, {Value: inDim}}}},
+		Outputs: []onnxValueInfo{{Name: "output", ElemType: onnxFloat}},
+	}}
+}
+
+func TestImportONNX(t *testing.T) {
+	tensors := []onnxTensor{
+		// 2 x 3 weights for a Gemm without transB, scaled by alpha
+		{Name: "w0", Dims: []int64{2, 3}, DataType: onnxFloat, Data: []float64{1, 2, 3, 4, 5, 6}},
+		{Name: "b0", Dims: []int64{1, 3}, DataType: onnxFloat, Data: []float64{0.5, -0.5, 1}},
+		// 3 x 1 weights for a MatMul followed by an Add taking the bias first
+		{Name: "w1", Dims: []int64{3, 1}, DataType: onnxFloat, Data: []float64{-1, 0, 2}},
+		{Name: "b1", Dims: []int64{1}, DataType: onnxFloat, Data: []float64{0.25}},
+	}
+	model := onnxChain(2, tensors,
+		onnxNode{Name: "gemm", OpType: "Gemm", Inputs: []string{"w0", "b0"}, Attrs: []onnxAttr{
+			{Name: "alpha", Type: onnxAttrFloat, F: 2}, {Name: "beta", Type: onnxAttrFloat, F: 0.5},
+		}},
+		onnxNode{Name: "relu0", OpType: "Relu"},
+		onnxNode{Name: "dropout", OpType: "Dropout"},
+		onnxNode{Name: "matmul", OpType: "MatMul", Inputs: []string{"w1"}},
+		onnxNode{Name: "add", OpType: "Add", Inputs: []string{"b1"}},
+		onnxNode{Name: "relu1", OpType: "Relu"},
+		onnxNode{Name: "identity", OpType: "Identity"},
+	)
+	model.Graph.Nodes[4].Inputs[0], model.Graph.Nodes[4].Inputs[1] = "b1", "matmul"
+	// Older models list the initializers among the inputs
+	model.Graph.Inputs = append(model.Graph.Inputs, onnxValueInfo{Name: "w0"}, onnxValueInfo{Name: "b1"})
+
+	m, err := UnmarshalONNX(model.marshal(nil))
+	if err != nil {
+		t.Fatalf("UnmarshalONNX() returned an error: %v", err)
+	}
+	want, err := NewMlpWith([]int{2, 3, 1}, ReLuAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	want.SetWeights([][]float64{{2, 8, 0.25, 4, 10, -0.25, 6, 12, 0.5}, {-1, 0, 2, 0.25}})
+	samePredictions(t, want, m, 0)
+}
+
+func TestImportONNXErrors(t *testing.T) {
+	w := func(name string, rows, cols int64) onnxTensor {
+		return onnxTensor{Name: name, Dims: []int64{rows, cols}, DataType: onnxDouble, Data: make([]float64, rows*cols)}
+	}
+	gemm := func(name, weights string) onnxNode {
+		return onnxNode{Name: name, OpType: "Gemm", Inputs: []string{weights}, Attrs: []onnxAttr{{Name: "transB", Type: onnxAttrInt, I: 1}}}
+	}
+	tensors := []onnxTensor{w("w0", 3, 2), w("w1", 1, 3), w("w2", 1, 4), w("w3", 0, 2)}
+
+	branching := onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Sigmoid"},
+		gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Sigmoid"})
+	branching.Graph.Nodes = append(branching.Graph.Nodes, onnxNode{Name: "other", OpType: "Relu", Inputs: []string{"gemm0"}, Outputs: []string{"other"}})
+	stray := onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Sigmoid"},
+		gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Sigmoid"})
+	stray.Graph.Nodes = append(stray.Graph.Nodes, onnxNode{Name: "stray", OpType: "Relu", Inputs: []string{"elsewhere"}, Outputs: []string{"stray"}})
+
+	for _, tc := range []struct {
+		name  string
+		model *onnxModel
+		err   string
+	}{
+		{"unsupported", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "tanh0", OpType: "Tanh"}),
+			"unsupported operator Tanh in node tanh0"},
+		{"no activation", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Relu"}, gemm("gemm1", "w1")),
+			"layer 1 applies no activation function"},
+		{"missing activation", onnxChain(2, tensors, gemm("gemm0", "w0"), gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Relu"}),
+			"layer 0 applies no activation function"},
+		{"mixed activations", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Relu"},
+			gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Sigmoid"}), "layer 1 applies sigmoid"},
+		{"mismatched dimensions", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Relu"},
+			gemm("gemm1", "w2"), onnxNode{Name: "act1", OpType: "Relu"}), "layer 1 takes 4 inputs, but layer 0 produces 3 outputs"},
+		{"wrong input", onnxChain(5, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Relu"},
+			gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Relu"}), "input input has 5 features"},
+		{"single layer", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Relu"}),
+			"an MLP needs at least a hidden and an output one"},
+		{"empty layer", onnxChain(2, tensors, gemm("gemm0", "w3"), onnxNode{Name: "act0", OpType: "Relu"}),
+			"every layer needs at least an input and a neuron"},
+		{"computed weights", onnxChain(2, tensors, gemm("gemm0", "w9")), "node gemm0 takes w9, which isn't an initializer"},
+		{"branching", branching, "the graph branches at gemm0"},
+		{"stray node", stray, "node stray doesn't lie on the path from input to output"},
+		{"softmax axis", onnxChain(2, tensors, gemm("gemm0", "w0"), onnxNode{Name: "act0", OpType: "Sigmoid"},
+			gemm("gemm1", "w1"), onnxNode{Name: "act1", OpType: "Sigmoid"},
+			onnxNode{Name: "softmax", OpType: "Softmax", Attrs: []onnxAttr{{Name: "axis", Type: onnxAttrInt, I: 0}}}),
+			"Softmax node softmax normalises over axis 0"},
+		{"zero epsilon", onnxChain(2, append(tensors, onnxTensor{Name: "g0", Dims: []int64{3}, DataType: onnxDouble, Data: make([]float64, 3)}),
+			gemm("gemm0", "w0"), onnxNode{Name: "norm0", OpType: "LayerNormalization", Inputs: []string{"g0"}, Attrs: []onnxAttr{{Name: "epsilon", Type: onnxAttrFloat}}}),
+			"LayerNormalization node norm0 has a non-positive epsilon 0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := UnmarshalONNX(tc.model.marshal(nil)); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("wrong error: %v, expected it to mention %q", err, tc.err)
+			}
+		})
+	}
+
+	if _, err := UnmarshalONNX([]byte{0x0a, 0xff}); err == nil || !strings.Contains(err.Error(), "couldn't decode") {
+		t.Errorf("UnmarshalONNX() accepted a malformed model: %v", err)
+	}
+}
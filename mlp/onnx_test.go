@@ -0,0 +1,197 @@
+package mlp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// exportONNX exports the MLP and parses the result back.
+func exportONNX(t *testing.T, m *Mlp, opts ONNXOptions) *onnxModel {
+	data, err := m.MarshalONNX(opts)
+	if err != nil {
+		t.Fatalf("MarshalONNX() returned an error: %v", err)
+	}
+	var model onnxModel
+	if err := model.unmarshal(data); err != nil {
+		t.Fatalf("couldn't parse the exported model: %v", err)
+	}
+	return &model
+}
+
+// opTypes returns the operator of each node, checking each one takes the
+// output of the previous one.
+func opTypes(t *testing.T, g *onnxGraph) []string {
+	var ops []string
+	prev := "input"
+	for _, n := range g.Nodes {
+		if len(n.Inputs) == 0 || n.Inputs[0] != prev || len(n.Outputs) != 1 {
+			t.Errorf("node %s doesn't take the output of the previous one, %s: %v -> %v", n.Name, prev, n.Inputs, n.Outputs)
+		}
+		ops, prev = append(ops, n.OpType), n.Outputs[0]
+	}
+	if prev != "output" {
+		t.Errorf("the last node produces %s instead of output", prev)
+	}
+	return ops
+}
+
+func initializers(g *onnxGraph) map[string]onnxTensor {
+	ts := map[string]onnxTensor{}
+	for _, t := range g.Initializers {
+		ts[t.Name] = t
+	}
+	return ts
+}
+
+func TestExportONNX(t *testing.T) {
+	m, err := NewMlpWith([]int{3, 4, 2}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	model := exportONNX(t, m, ONNXOptions{})
+
+	if model.IRVersion != 7 || !reflect.DeepEqual(model.Opsets, []onnxOpset{{Version: 13}}) || model.ProducerName != "mlp-go" {
+		t.Errorf("wrong model header: IR %d, opsets %v, producer %q", model.IRVersion, model.Opsets, model.ProducerName)
+	}
+	g := &model.Graph
+	wantIn := []onnxValueInfo{{Name: "input", ElemType: onnxDouble, Dims: []onnxDim{{Param: "batch"}, {Value: 3}}}}
+	wantOut := []onnxValueInfo{{Name: "output", ElemType: onnxDouble, Dims: []onnxDim{{Param: "batch"}, {Value: 2}}}}
+	if !reflect.DeepEqual(g.Inputs, wantIn) || !reflect.DeepEqual(g.Outputs, wantOut) {
+		t.Errorf("wrong graph inputs or outputs: %+v %+v", g.Inputs, g.Outputs)
+	}
+
+	if ops := opTypes(t, g); !reflect.DeepEqual(ops, []string{"Gemm", "Sigmoid", "Gemm", "Sigmoid"}) {
+		t.Errorf("wrong nodes: %v", ops)
+	}
+	if attrs := g.Nodes[0].Attrs; len(attrs) != 1 || attrs[0].Name != "transB" || attrs[0].I != 1 {
+		t.Errorf("wrong Gemm attributes: %+v", attrs)
+	}
+
+	ts := initializers(g)
+	for i, w := range m.Weights {
+		out, in := w.Dims()
+		in--
+		prefix := fmt.Sprintf("dense%d", i)
+		weight, bias := ts[prefix+".weight"], ts[prefix+".bias"]
+		if !reflect.DeepEqual(weight.Dims, []int64{int64(out), int64(in)}) || !reflect.DeepEqual(bias.Dims, []int64{int64(out)}) {
+			t.Fatalf("wrong shapes for layer %d: %v %v", i, weight.Dims, bias.Dims)
+		}
+		if weight.DataType != onnxDouble || bias.DataType != onnxDouble {
+			t.Errorf("wrong data types for layer %d: %d %d", i, weight.DataType, bias.DataType)
+		}
+		for j := 0; j < out; j++ {
+			for k := 0; k < in; k++ {
+				if weight.Data[j*in+k] != w.At(j, k) {
+					t.Errorf("weight (%d, %d) of layer %d is %v, expected %v", j, k, i, weight.Data[j*in+k], w.At(j, k))
+				}
+			}
+			if bias.Data[j] != w.At(j, in) {
+				t.Errorf("bias %d of layer %d is %v, expected %v", j, i, bias.Data[j], w.At(j, in))
+			}
+		}
+	}
+
+	fpath := filepath.Join(t.TempDir(), "mlp.onnx")
+	if err := m.ExportONNX(fpath, ONNXOptions{}); err != nil {
+		t.Fatalf("ExportONNX() returned an error: %v", err)
+	}
+	written, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("couldn't read the exported model: %v", err)
+	}
+	if data, _ := m.MarshalONNX(ONNXOptions{}); !bytes.Equal(written, data) {
+		t.Errorf("ExportONNX() and MarshalONNX() disagree")
+	}
+}
+
+func TestExportONNXMatMul(t *testing.T) {
+	m, err := NewMlpWith([]int{3, 4, 2}, ReLuAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	model := exportONNX(t, m, ONNXOptions{MatMul: true, Softmax: true, Float32: true})
+	g := &model.Graph
+
+	want := []string{"MatMul", "Add", "Relu", "MatMul", "Add", "Relu", "Softmax"}
+	if ops := opTypes(t, g); !reflect.DeepEqual(ops, want) {
+		t.Errorf("wrong nodes: %v, expected %v", ops, want)
+	}
+	if g.Inputs[0].ElemType != onnxFloat || g.Outputs[0].ElemType != onnxFloat {
+		t.Errorf("the graph doesn't take and produce floats: %+v %+v", g.Inputs, g.Outputs)
+	}
+
+	// MatMul takes the weights transposed
+	weight := initializers(g)["dense0.weight"]
+	if !reflect.DeepEqual(weight.Dims, []int64{3, 4}) || weight.DataType != onnxFloat {
+		t.Fatalf("wrong weights: %v %d", weight.Dims, weight.DataType)
+	}
+	for k := 0; k < 3; k++ {
+		for j := 0; j < 4; j++ {
+			if want := float64(float32(m.Weights[0].At(j, k))); weight.Data[k*4+j] != want {
+				t.Errorf("weight (%d, %d) is %v, expected %v", k, j, weight.Data[k*4+j], want)
+			}
+		}
+	}
+}
+
+func TestExportONNXNorms(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 3, 3, 1}, UnitStepAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	if err := m.SetNorm(0, BatchNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+	if err := m.SetNorm(1, LayerNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+	m.Norms[0].RunningMean[1], m.Norms[0].RunningVar[2] = 0.5, 2
+	model := exportONNX(t, m, ONNXOptions{})
+
+	if model.IRVersion != 8 || model.Opsets[0].Version != 17 {
+		t.Errorf("layer normalisation needs opset 17, got IR %d and opsets %v", model.IRVersion, model.Opsets)
+	}
+	want := []string{"Gemm", "BatchNormalization", "Sign", "Relu", "Gemm", "LayerNormalization", "Sign", "Relu", "Gemm", "Sign", "Relu"}
+	if ops := opTypes(t, &model.Graph); !reflect.DeepEqual(ops, want) {
+		t.Errorf("wrong nodes: %v, expected %v", ops, want)
+	}
+
+	bn := model.Graph.Nodes[1]
+	if len(bn.Inputs) != 5 || len(bn.Attrs) != 1 || bn.Attrs[0].Name != "epsilon" || bn.Attrs[0].F != float32(m.Norms[0].Eps) {
+		t.Errorf("wrong batch normalisation node: %+v", bn)
+	}
+	ts := initializers(&model.Graph)
+	for name, want := range map[string][]float64{
+		"norm0.gamma": m.Norms[0].Gamma, "norm0.running_mean": m.Norms[0].RunningMean, "norm0.running_var": m.Norms[0].RunningVar,
+		"norm1.gamma": m.Norms[1].Gamma, "norm1.beta": m.Norms[1].Beta,
+	} {
+		if !reflect.DeepEqual(ts[name].Data, want) {
+			t.Errorf("wrong %s: %v, expected %v", name, ts[name].Data, want)
+		}
+	}
+}
+
+func TestExportONNXErrors(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 2, 1}, Activation{Name: "tanh", F: math.Tanh}, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	if _, err := m.MarshalONNX(ONNXOptions{}); err == nil {
+		t.Errorf("MarshalONNX() exported a custom activation function")
+	}
+
+	m.ActFunc = SigmoidAct
+	data, err := m.MarshalONNX(ONNXOptions{})
+	if err != nil {
+		t.Fatalf("MarshalONNX() returned an error: %v", err)
+	}
+	var model onnxModel
+	if err := model.unmarshal(data[:len(data)-3]); err == nil {
+		t.Errorf("unmarshal() accepted a truncated model")
+	}
+}
@@ -0,0 +1,326 @@
+package mlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"time"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// mlpJSON is how an MLP is laid out when persisting it. Each weight matrix is
+// stored row after row.
+type mlpJSON struct {
+	Dims           []int
+	Activation     string
+	Weights        [][]float64
+	Regularization Regularization
+	GradClip       GradClip
+	Dropout        []float64 `json:",omitempty"`
+	Norms          []*Norm   `json:",omitempty"`
+}
+
+func (mlp *Mlp) MarshalJSON() ([]byte, error) {
+	if _, ok := Activations[mlp.ActFunc.Name]; !ok {
+		return nil, fmt.Errorf("can't persist custom activation function %q", mlp.ActFunc.Name)
+	}
+
+	m := mlpJSON{
+		Dims:           append(append([]int{mlp.InDim}, mlp.HiddenDim...), mlp.OutDim),
+		Activation:     mlp.ActFunc.Name,
+		Regularization: mlp.Regularization,
+		GradClip:       mlp.GradClip,
+		Dropout:        mlp.Dropout,
+		Norms:          mlp.Norms,
+	}
+	for _, w := range mlp.Weights {
+		m.Weights = append(m.Weights, w.RawMatrix().Data)
+	}
+
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON restores a persisted MLP, checking it's consistent.
+func (mlp *Mlp) UnmarshalJSON(data []byte) error {
+	var m mlpJSON
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+
+	act, ok := Activations[m.Activation]
+	if !ok {
+		return fmt.Errorf("unknown activation function %q", m.Activation)
+	}
+
+	restored, err := NewMlpWith(m.Dims, act, 1)
+	if err != nil {
+		return err
+	}
+
+	if len(m.Weights) != len(restored.Weights) {
+		return fmt.Errorf("got %d weight matrices for %d layers", len(m.Weights), len(restored.Weights))
+	}
+	for i, w := range restored.Weights {
+		r, c := w.Dims()
+		if len(m.Weights[i]) != r*c {
+			return fmt.Errorf("weight matrix %d should hold %d x %d weights, but it has %d", i, r, c, len(m.Weights[i]))
+		}
+	}
+	restored.SetWeights(m.Weights)
+
+	if len(m.Dropout) > restored.NHidden {
+		return fmt.Errorf("got %d dropout rates for %d hidden layers", len(m.Dropout), restored.NHidden)
+	}
+	for i, rate := range m.Dropout {
+		if !(rate >= 0 && rate < 1) {
+			return fmt.Errorf("the dropout rate of hidden layer %d should be in [0, 1), but it's %v", i, rate)
+		}
+	}
+	if len(m.Norms) > restored.NHidden {
+		return fmt.Errorf("got %d normalisations for %d hidden layers", len(m.Norms), restored.NHidden)
+	}
+	for i, n := range m.Norms {
+		if n == nil || n.Kind == NoNorm {
+			m.Norms[i] = nil
+			continue
+		}
+		if err := n.validate(restored.HiddenDim[i]); err != nil {
+			return fmt.Errorf("hidden layer %d: %v", i, err)
+		}
+	}
+
+	restored.Regularization, restored.GradClip, restored.Dropout, restored.Norms = m.Regularization, m.GradClip, m.Dropout, m.Norms
+
+	*mlp = *restored
+	return nil
+}
+
+// Save persists the MLP as JSON into the given file.
+func (mlp *Mlp) Save(fpath string) error {
+	data, err := json.Marshal(mlp)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the MLP: %v", err)
+	}
+	return ioutil.WriteFile(fpath, data, 0644)
+}
+
+// Load restores an MLP persisted with Save.
+func Load(fpath string) (*Mlp, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var mlp Mlp
+	if err := json.Unmarshal(data, &mlp); err != nil {
+		return nil, fmt.Errorf("couldn't decode the MLP: %v", err)
+	}
+	return &mlp, nil
+}
+
+// layerJSON is how a layer is laid out when persisting a Sequential model:
+// Layer holds its configuration and parameters and Type tells how to read it.
+type layerJSON struct {
+	Type  string
+	Layer json.RawMessage
+}
+
+type denseJSON struct {
+	In, Out int
+	Weights []float64
+}
+
+type residualJSON struct {
+	Block      layerJSON
+	Projection *layerJSON `json:",omitempty"`
+}
+
+func marshalLayer(l Layer) (layerJSON, error) {
+	var (
+		kind string
+		v    interface{}
+	)
+
+	switch l := l.(type) {
+	case *Dense:
+		in, out := l.Dims()
+		kind, v = "dense", denseJSON{In: in, Out: out, Weights: mat.DenseCopyOf(l.W).RawMatrix().Data}
+	case *ActivationLayer:
+		if _, ok := Activations[l.Act.Name]; !ok {
+			return layerJSON{}, fmt.Errorf("can't persist custom activation function %q", l.Act.Name)
+		}
+		kind, v = "activation", l.Act.Name
+	case *Dropout:
+		kind, v = "dropout", l.Rate
+	case *Norm:
+		kind, v = "norm", l
+	case *Residual:
+		block, err := marshalLayer(l.Block)
+		if err != nil {
+			return layerJSON{}, err
+		}
+		r := residualJSON{Block: block}
+		if l.Projection != nil {
+			proj, err := marshalLayer(l.Projection)
+			if err != nil {
+				return layerJSON{}, err
+			}
+			r.Projection = &proj
+		}
+		kind, v = "residual", r
+	case *Sequential:
+		var layers []layerJSON
+		for _, sub := range l.Layers {
+			m, err := marshalLayer(sub)
+			if err != nil {
+				return layerJSON{}, err
+			}
+			layers = append(layers, m)
+		}
+		kind, v = "sequential", layers
+	default:
+		return layerJSON{}, fmt.Errorf("can't persist layer of type %T", l)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return layerJSON{}, err
+	}
+	return layerJSON{Type: kind, Layer: data}, nil
+}
+
+func unmarshalLayer(m layerJSON) (Layer, error) {
+	switch m.Type {
+	case "dense":
+		var d denseJSON
+		if err := json.Unmarshal(m.Layer, &d); err != nil {
+			return nil, err
+		}
+		if d.In < 1 || d.Out < 1 {
+			return nil, fmt.Errorf("dense layers need positive dimensions, but got %d -> %d", d.In, d.Out)
+		}
+		if len(d.Weights) != d.Out*(d.In+1) {
+			return nil, fmt.Errorf("a %d -> %d dense layer should hold %d weights, but it has %d", d.In, d.Out, d.Out*(d.In+1), len(d.Weights))
+		}
+		return &Dense{W: mat.NewDense(d.Out, d.In+1, d.Weights)}, nil
+	case "activation":
+		var name string
+		if err := json.Unmarshal(m.Layer, &name); err != nil {
+			return nil, err
+		}
+		act, ok := Activations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown activation function %q", name)
+		}
+		return NewActivationLayer(act), nil
+	case "dropout":
+		var rate float64
+		if err := json.Unmarshal(m.Layer, &rate); err != nil {
+			return nil, err
+		}
+		if rate < 0 || rate >= 1 {
+			return nil, fmt.Errorf("dropout rates should lie in [0, 1), but got %g", rate)
+		}
+		return NewDropout(rate, rand.New(rand.NewSource(time.Now().UnixNano()))), nil
+	case "norm":
+		var n Norm
+		if err := json.Unmarshal(m.Layer, &n); err != nil {
+			return nil, err
+		}
+		if n.Kind == NoNorm {
+			return nil, fmt.Errorf("inconsistent %s normalisation", n.Kind)
+		}
+		if err := n.validate(len(n.Gamma)); err != nil {
+			return nil, err
+		}
+		return &n, nil
+	case "residual":
+		var r residualJSON
+		if err := json.Unmarshal(m.Layer, &r); err != nil {
+			return nil, err
+		}
+		block, err := unmarshalLayer(r.Block)
+		if err != nil {
+			return nil, err
+		}
+		res := NewResidual(block, nil)
+		if r.Projection != nil {
+			proj, err := unmarshalLayer(*r.Projection)
+			if err != nil {
+				return nil, err
+			}
+			d, ok := proj.(*Dense)
+			if !ok {
+				return nil, fmt.Errorf("residual projections should be dense layers, but got a %s one", r.Projection.Type)
+			}
+			res.Projection = d
+		}
+		return res, nil
+	case "sequential":
+		var layers []layerJSON
+		if err := json.Unmarshal(m.Layer, &layers); err != nil {
+			return nil, err
+		}
+		s := NewSequential()
+		for _, sub := range layers {
+			l, err := unmarshalLayer(sub)
+			if err != nil {
+				return nil, err
+			}
+			s.Layers = append(s.Layers, l)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown layer type %q", m.Type)
+}
+
+// MarshalJSON persists every built-in layer, failing for custom ones.
+func (s *Sequential) MarshalJSON() ([]byte, error) {
+	m, err := marshalLayer(s)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
+}
+
+func (s *Sequential) UnmarshalJSON(data []byte) error {
+	var m layerJSON
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if m.Type != "sequential" {
+		return fmt.Errorf("expected a sequential model, but got a %s layer", m.Type)
+	}
+
+	l, err := unmarshalLayer(m)
+	if err != nil {
+		return err
+	}
+	*s = *l.(*Sequential)
+	return nil
+}
+
+// Save persists the model as JSON into the given file.
+func (s *Sequential) Save(fpath string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("couldn't encode the model: %v", err)
+	}
+	return ioutil.WriteFile(fpath, data, 0644)
+}
+
+// LoadSequential restores a model persisted with Sequential.Save.
+func LoadSequential(fpath string) (*Sequential, error) {
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Sequential
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("couldn't decode the model: %v", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,82 @@
+package mlp
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestSaveLoad(t *testing.T) {
+	m, err := NewMlpWith([]int{3, 5, 4, 2}, ReLuAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	if err := m.SetNorm(0, BatchNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+	if err := m.SetNorm(1, LayerNorm); err != nil {
+		t.Fatalf("SetNorm() returned an error: %v", err)
+	}
+	m.Dropout = []float64{0.2}
+	m.Regularization = Regularization{L2: 0.01, MaxNorm: 3}
+
+	m.AdaptBatch([][]float64{{1, 0, 1}, {0, 1, 0}, {1, 1, 1}}, [][]float64{{1, 0}, {0, 1}, {1, 1}}, 0.1)
+
+	fpath := filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(fpath); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+
+	restored, err := Load(fpath)
+	if err != nil {
+		t.Fatalf("Load() returned an error: %v", err)
+	}
+
+	params, restoredParams := m.params(), restored.params()
+	if len(params) != len(restoredParams) {
+		t.Fatalf("got %d parameters back instead of %d", len(restoredParams), len(params))
+	}
+	for i, p := range params {
+		if !mat.Equal(p, restoredParams[i]) {
+			t.Errorf("parameter %d mismatch: %6.3f != %6.3f", i,
+				mat.Formatted(restoredParams[i], mat.FormatMATLAB()), mat.Formatted(p, mat.FormatMATLAB()))
+		}
+	}
+	if restored.Regularization != m.Regularization || len(restored.Dropout) != 1 || restored.Dropout[0] != 0.2 {
+		t.Errorf("configuration mismatch: %+v / %v", restored.Regularization, restored.Dropout)
+	}
+
+	m.Training, restored.Training = false, false
+	output, _, _ := m.ComputeActivation([]float64{0.5, -1, 2})
+	want := append([]float64(nil), output...)
+	got, _, _ := restored.ComputeActivation([]float64{0.5, -1, 2})
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("output %d mismatch: %g != %g", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"activation":         `{"Dims": [2, 2, 1], "Activation": "tanh", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]]}`,
+		"dimensions":         `{"Dims": [2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3]]}`,
+		"weights":            `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5], [1, 2, 3]]}`,
+		"layers":             `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6]]}`,
+		"norm":               `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Norms": [{"Kind": "layer", "Gamma": [1], "Beta": [0]}]}`,
+		"norm kind":          `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Norms": [{"Kind": "group"}]}`,
+		"running stat":       `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Norms": [{"Kind": "batch", "Gamma": [1, 1], "Beta": [0, 0]}]}`,
+		"zero dimension":     `{"Dims": [2, 0, 1], "Activation": "sigmoid", "Weights": [[], [1]]}`,
+		"negative dimension": `{"Dims": [2, -1, 1], "Activation": "sigmoid", "Weights": [[], []]}`,
+		"dropout rate":       `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Dropout": [1]}`,
+		"negative dropout":   `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Dropout": [-0.5]}`,
+		"norm epsilon":       `{"Dims": [2, 2, 1], "Activation": "sigmoid", "Weights": [[1, 2, 3, 4, 5, 6], [1, 2, 3]], "Norms": [{"Kind": "layer", "Gamma": [1, 1], "Beta": [0, 0], "Eps": 0}]}`,
+	} {
+		var m Mlp
+		if err := json.Unmarshal([]byte(data), &m); err == nil {
+			t.Errorf("an invalid %s was accepted", name)
+		}
+	}
+}
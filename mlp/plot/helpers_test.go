@@ -0,0 +1,66 @@
+package plot
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"testing"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+// trainedXor fits a 2-5-1 MLP to 80 out of 100 XOR samples for 5 epochs,
+// recording its loss and accuracy on the remaining 20. It returns the MLP,
+// its history and the samples with their labels.
+func trainedXor(t *testing.T) (*mlp.Mlp, *mlp.History, [][]float64, []float64) {
+	t.Helper()
+	inputs, labels := mlp.GenXor(100, 0.1)
+	targets := make([][]float64, len(labels))
+	for i, l := range labels {
+		targets[i] = []float64{l}
+	}
+
+	m, err := mlp.NewMlpWith([]int{2, 5, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	tr, err := mlp.NewTrainer(10, 1, 0.5, 1)
+	if err != nil {
+		t.Fatalf("NewTrainer() returned an error: %v", err)
+	}
+	rec := mlp.Recorder{ValInputs: inputs[80:], ValTargets: targets[80:], Metrics: []mlp.Metric{mlp.AccuracyMetric}}
+	history, err := tr.Fit(m, inputs[:80], targets[:80], 5, &rec)
+	if err != nil {
+		t.Fatalf("Fit() returned an error: %v", err)
+	}
+	return m, history, inputs, labels
+}
+
+// smallImages returns n rows x cols images whose pixels cycle through 0, 1/2
+// and 1.
+func smallImages(n, rows, cols int) mlp.Images {
+	imgs := mlp.Images{N: uint32(n), ImgRows: uint32(rows), ImgCols: uint32(cols), Images: make([][][]float64, n)}
+	for i := range imgs.Images {
+		imgs.Images[i] = make([][]float64, rows)
+		for r := range imgs.Images[i] {
+			imgs.Images[i][r] = make([]float64, cols)
+			for c := range imgs.Images[i][r] {
+				imgs.Images[i][r][c] = float64((i+r+c)%3) / 2
+			}
+		}
+	}
+	return imgs
+}
+
+func decodePNG(t *testing.T, fpath string) image.Image {
+	f, err := os.Open(fpath)
+	if err != nil {
+		t.Fatalf("couldn't open %s: %v", fpath, err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("%s isn't a valid PNG: %v", fpath, err)
+	}
+	return img
+}
@@ -0,0 +1,400 @@
+// Package plot draws the training histories and decision boundaries of MLPs
+// and lays out images in contact sheets, as PNG or SVG files.
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+// The colour of each series or class, cycling when there are more of them.
+var palette = []color.RGBA{
+	{31, 119, 180, 255}, {255, 127, 14, 255}, {44, 160, 44, 255},
+	{214, 39, 40, 255}, {148, 103, 189, 255}, {140, 86, 75, 255},
+}
+
+// classColor picks the colour of a class, which may well be negative.
+func classColor(class int) color.RGBA {
+	n := len(palette)
+	return palette[(class%n+n)%n]
+}
+
+var (
+	white = color.RGBA{255, 255, 255, 255}
+	black = color.RGBA{0, 0, 0, 255}
+	grey  = color.RGBA{200, 200, 200, 255}
+)
+
+// canvas is what plots are drawn on. Coordinates are in pixels with the
+// origin at the top left corner.
+type canvas interface {
+	rect(x0, y0, x1, y1 float64, fill color.RGBA)
+	polyline(xs, ys []float64, stroke color.RGBA, width float64)
+	circle(x, y, r float64, fill, stroke color.RGBA)
+	text(x, y float64, s string, anchor string)
+	save(fpath string) error
+}
+
+// newCanvas picks the format from the extension of fpath: either PNG or SVG.
+func newCanvas(fpath string, w, h int) (canvas, error) {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".png":
+		return newPngCanvas(w, h), nil
+	case ".svg":
+		return newSvgCanvas(w, h), nil
+	}
+	return nil, fmt.Errorf("can't tell the format of %s: use either a .png or a .svg extension", fpath)
+}
+
+// pngCanvas rasterises plots, drawing text with the bitmap font.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func newPngCanvas(w, h int) *pngCanvas {
+	c := pngCanvas{img: image.NewRGBA(image.Rect(0, 0, w, h))}
+	c.rect(0, 0, float64(w), float64(h), white)
+	return &c
+}
+
+func (c *pngCanvas) rect(x0, y0, x1, y1 float64, fill color.RGBA) {
+	for y := int(math.Round(y0)); y < int(math.Round(y1)); y++ {
+		for x := int(math.Round(x0)); x < int(math.Round(x1)); x++ {
+			c.img.SetRGBA(x, y, fill)
+		}
+	}
+}
+
+// disc fills a disc centred at (x, y).
+func (c *pngCanvas) disc(x, y, r float64, fill color.RGBA) {
+	for py := int(math.Floor(y - r)); py <= int(math.Ceil(y+r)); py++ {
+		for px := int(math.Floor(x - r)); px <= int(math.Ceil(x+r)); px++ {
+			if dx, dy := float64(px)+0.5-x, float64(py)+0.5-y; dx*dx+dy*dy <= r*r {
+				c.img.SetRGBA(px, py, fill)
+			}
+		}
+	}
+}
+
+func (c *pngCanvas) polyline(xs, ys []float64, stroke color.RGBA, width float64) {
+	r := math.Max(width/2, 0.5)
+	for i := range xs {
+		if i == 0 {
+			c.disc(xs[0], ys[0], r, stroke)
+			continue
+		}
+		// Stamp discs along the segment every half a pixel
+		dx, dy := xs[i]-xs[i-1], ys[i]-ys[i-1]
+		n := int(math.Ceil(math.Hypot(dx, dy) * 2))
+		for s := 1; s <= n; s++ {
+			t := float64(s) / float64(n)
+			c.disc(xs[i-1]+t*dx, ys[i-1]+t*dy, r, stroke)
+		}
+	}
+}
+
+func (c *pngCanvas) circle(x, y, r float64, fill, stroke color.RGBA) {
+	c.disc(x, y, r, stroke)
+	c.disc(x, y, r-1, fill)
+}
+
+// text draws s upper-cased with its baseline at y, just like SVG does.
+func (c *pngCanvas) text(x, y float64, s string, anchor string) {
+	const scale = 2
+	switch w := float64(textWidth(s, scale)); anchor {
+	case "middle":
+		x -= w / 2
+	case "end":
+		x -= w
+	}
+	drawText(c.img, s, int(math.Round(x)), int(math.Round(y))-5*scale, scale, black)
+}
+
+func (c *pngCanvas) save(fpath string) error {
+	return writePNG(c.img, fpath)
+}
+
+func writePNG(img image.Image, fpath string) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type svgCanvas struct {
+	w, h int
+	b    strings.Builder
+}
+
+func newSvgCanvas(w, h int) *svgCanvas {
+	c := svgCanvas{w: w, h: h}
+	fmt.Fprintf(&c.b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\">\n", w, h, w, h)
+	c.rect(0, 0, float64(w), float64(h), white)
+	return &c
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (c *svgCanvas) rect(x0, y0, x1, y1 float64, fill color.RGBA) {
+	fmt.Fprintf(&c.b, "<rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\" fill=\"%s\"/>\n", x0, y0, x1-x0, y1-y0, svgColor(fill))
+}
+
+func (c *svgCanvas) polyline(xs, ys []float64, stroke color.RGBA, width float64) {
+	points := make([]string, len(xs))
+	for i := range xs {
+		points[i] = fmt.Sprintf("%.2f,%.2f", xs[i], ys[i])
+	}
+	fmt.Fprintf(&c.b, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"%g\"/>\n", strings.Join(points, " "), svgColor(stroke), width)
+}
+
+func (c *svgCanvas) circle(x, y, r float64, fill, stroke color.RGBA) {
+	fmt.Fprintf(&c.b, "<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%g\" fill=\"%s\" stroke=\"%s\"/>\n", x, y, r, svgColor(fill), svgColor(stroke))
+}
+
+func (c *svgCanvas) text(x, y float64, s string, anchor string) {
+	s = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+	fmt.Fprintf(&c.b, "<text x=\"%.2f\" y=\"%.2f\" font-family=\"sans-serif\" font-size=\"12\" text-anchor=\"%s\">%s</text>\n", x, y, anchor, s)
+}
+
+func (c *svgCanvas) save(fpath string) error {
+	return ioutil.WriteFile(fpath, []byte(c.b.String()+"</svg>\n"), 0644)
+}
+
+// axes maps data coordinates within [xMin, xMax] x [yMin, yMax] into the
+// pixel box [x0, x1] x [y0, y1], leaving room for the tick labels.
+type axes struct {
+	x0, y0, x1, y1         float64
+	xMin, xMax, yMin, yMax float64
+}
+
+func newAxes(x0, y0, x1, y1, xMin, xMax, yMin, yMax float64) axes {
+	// Avoid dividing by 0 for flat data
+	if xMax == xMin {
+		xMin, xMax = xMin-0.5, xMax+0.5
+	}
+	if yMax == yMin {
+		yMin, yMax = yMin-0.5, yMax+0.5
+	}
+	return axes{x0 + 50, y0 + 30, x1 - 15, y1 - 35, xMin, xMax, yMin, yMax}
+}
+
+func (a axes) px(x float64) float64 {
+	return a.x0 + (x-a.xMin)/(a.xMax-a.xMin)*(a.x1-a.x0)
+}
+
+func (a axes) py(y float64) float64 {
+	return a.y1 - (y-a.yMin)/(a.yMax-a.yMin)*(a.y1-a.y0)
+}
+
+// draw draws the frame of the axes with 5 ticks on each together with the
+// title and the label of the x axis.
+func (a axes) draw(c canvas, title, xLabel string) {
+	for i := 0; i <= 4; i++ {
+		x := a.xMin + float64(i)/4*(a.xMax-a.xMin)
+		c.polyline([]float64{a.px(x), a.px(x)}, []float64{a.y1, a.y1 + 4}, black, 1)
+		c.text(a.px(x), a.y1+16, fmt.Sprintf("%.3g", x), "middle")
+
+		y := a.yMin + float64(i)/4*(a.yMax-a.yMin)
+		c.polyline([]float64{a.x0 - 4, a.x0}, []float64{a.py(y), a.py(y)}, black, 1)
+		c.text(a.x0-6, a.py(y)+4, fmt.Sprintf("%.3g", y), "end")
+	}
+	c.polyline([]float64{a.x0, a.x0, a.x1, a.x1, a.x0}, []float64{a.y0, a.y1, a.y1, a.y0, a.y0}, black, 1)
+	c.text((a.x0+a.x1)/2, a.y0-10, title, "middle")
+	c.text((a.x0+a.x1)/2, a.y1+30, xLabel, "middle")
+}
+
+// drawCurves plots the given metrics of the history against the step within
+// the box, with a legend in its top right corner.
+func drawCurves(c canvas, h *mlp.History, names []string, title string, x0, y0, x1, y1 float64) {
+	xMin, xMax, yMin, yMax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, r := range h.Records {
+		xMin, xMax = math.Min(xMin, float64(r.Step)), math.Max(xMax, float64(r.Step))
+		for _, name := range names {
+			if v, ok := r.Metrics[name]; ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+				yMin, yMax = math.Min(yMin, v), math.Max(yMax, v)
+			}
+		}
+	}
+	if math.IsInf(xMin, 0) || math.IsInf(yMin, 0) {
+		xMin, xMax, yMin, yMax = 0, 1, 0, 1
+	}
+
+	a := newAxes(x0, y0, x1, y1, xMin, xMax, yMin, yMax)
+	a.draw(c, title, "step")
+
+	for i, name := range names {
+		var xs, ys []float64
+		for _, r := range h.Records {
+			if v, ok := r.Metrics[name]; ok && !math.IsNaN(v) && !math.IsInf(v, 0) {
+				xs, ys = append(xs, a.px(float64(r.Step))), append(ys, a.py(v))
+			}
+		}
+		col := palette[i%len(palette)]
+		c.polyline(xs, ys, col, 2)
+
+		ly := a.y0 + 12 + float64(i)*16
+		c.polyline([]float64{a.x1 - 110, a.x1 - 90}, []float64{ly, ly}, col, 2)
+		c.text(a.x1-85, ly+4, name, "start")
+	}
+}
+
+// historyPanels splits the metrics of the history into losses and the rest.
+func historyPanels(h *mlp.History) (losses, others []string) {
+	for _, name := range h.MetricNames() {
+		if strings.HasSuffix(name, "loss") {
+			losses = append(losses, name)
+		} else {
+			others = append(others, name)
+		}
+	}
+	return losses, others
+}
+
+// drawHistory stacks a panel with the losses on top of one with the rest of
+// the metrics, if any.
+func drawHistory(c canvas, h *mlp.History, x0, y0, x1, y1 float64) {
+	losses, others := historyPanels(h)
+	if len(others) == 0 {
+		drawCurves(c, h, losses, "loss", x0, y0, x1, y1)
+		return
+	}
+	mid := (y0 + y1) / 2
+	drawCurves(c, h, losses, "loss", x0, y0, x1, mid)
+	drawCurves(c, h, others, "metrics", x0, mid, x1, y1)
+}
+
+// PlotHistory renders the loss curves of a training history and, below them,
+// the curves of the rest of the metrics. The format is chosen through the
+// extension of fpath: either .png or .svg.
+func PlotHistory(h *mlp.History, fpath string) error {
+	height := 320
+	if _, others := historyPanels(h); len(others) > 0 {
+		height = 640
+	}
+
+	c, err := newCanvas(fpath, 640, height)
+	if err != nil {
+		return err
+	}
+	drawHistory(c, h, 0, 0, 640, float64(height))
+	return c.save(fpath)
+}
+
+// drawDecisionBoundary shades each region of the plane by the class the MLP
+// predicts for it and overlays the given 2-D points coloured by their labels.
+func drawDecisionBoundary(c canvas, m *mlp.Mlp, inputs [][]float64, labels []float64, x0, y0, x1, y1 float64) error {
+	if m.InDim != 2 {
+		return fmt.Errorf("decision boundaries can only be drawn for 2-D inputs, but the MLP takes %d", m.InDim)
+	}
+	if len(inputs) != len(labels) {
+		return fmt.Errorf("got %d inputs but %d labels", len(inputs), len(labels))
+	}
+
+	xMin, xMax, yMin, yMax := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, in := range inputs {
+		xMin, xMax = math.Min(xMin, in[0]), math.Max(xMax, in[0])
+		yMin, yMax = math.Min(yMin, in[1]), math.Max(yMax, in[1])
+	}
+	if len(inputs) == 0 {
+		xMin, xMax, yMin, yMax = 0, 1, 0, 1
+	}
+	xPad, yPad := (xMax-xMin)/10, (yMax-yMin)/10
+	a := newAxes(x0, y0, x1, y1, xMin-xPad, xMax+xPad, yMin-yPad, yMax+yPad)
+
+	// Classify the centre of each cell of a grid over the plot
+	const cells = 100
+	grid := make([][]float64, 0, cells*cells)
+	for i := 0; i < cells; i++ {
+		for j := 0; j < cells; j++ {
+			grid = append(grid, []float64{
+				a.xMin + (float64(j)+0.5)/cells*(a.xMax-a.xMin),
+				a.yMin + (float64(i)+0.5)/cells*(a.yMax-a.yMin),
+			})
+		}
+	}
+	outputs, err := m.Predictor().PredictBatch(grid)
+	if err != nil {
+		return err
+	}
+
+	cw, ch := (a.x1-a.x0)/cells, (a.y1-a.y0)/cells
+	for k, out := range outputs {
+		i, j := k/cells, k%cells
+		col := lighten(classColor(mlp.PredictedClass(out)))
+		c.rect(a.x0+float64(j)*cw, a.y1-float64(i+1)*ch, a.x0+float64(j+1)*cw, a.y1-float64(i)*ch, col)
+	}
+
+	a.draw(c, "decision boundary", "x1")
+	for i, in := range inputs {
+		c.circle(a.px(in[0]), a.py(in[1]), 4, classColor(int(math.Round(labels[i]))), white)
+	}
+
+	// Legend with every class
+	classes := map[int]bool{}
+	for _, l := range labels {
+		classes[int(math.Round(l))] = true
+	}
+	var sorted []int
+	for class := range classes {
+		sorted = append(sorted, class)
+	}
+	sort.Ints(sorted)
+	for i, class := range sorted {
+		ly := a.y0 + 12 + float64(i)*16
+		c.circle(a.x1-90, ly, 4, classColor(class), white)
+		c.text(a.x1-80, ly+4, fmt.Sprintf("class %d", class), "start")
+	}
+
+	return nil
+}
+
+func lighten(c color.RGBA) color.RGBA {
+	mix := func(v uint8) uint8 { return uint8(255 - (255-int(v))*35/100) }
+	return color.RGBA{mix(c.R), mix(c.G), mix(c.B), 255}
+}
+
+// PlotDecisionBoundary renders the classes the MLP, which must take 2-D inputs,
+// predicts over the region around the given points, overlaying the latter
+// coloured by their labels. The format is chosen through the extension of
+// fpath: either .png or .svg.
+func PlotDecisionBoundary(m *mlp.Mlp, inputs [][]float64, labels []float64, fpath string) error {
+	c, err := newCanvas(fpath, 520, 520)
+	if err != nil {
+		return err
+	}
+	if err := drawDecisionBoundary(c, m, inputs, labels, 0, 0, 520, 520); err != nil {
+		return err
+	}
+	return c.save(fpath)
+}
+
+// PlotTraining puts the plots of PlotHistory and PlotDecisionBoundary side by
+// side in a single figure.
+func PlotTraining(h *mlp.History, m *mlp.Mlp, inputs [][]float64, labels []float64, fpath string) error {
+	c, err := newCanvas(fpath, 1160, 640)
+	if err != nil {
+		return err
+	}
+	drawHistory(c, h, 0, 0, 640, 640)
+	if err := drawDecisionBoundary(c, m, inputs, labels, 640, 60, 1160, 580); err != nil {
+		return err
+	}
+	return c.save(fpath)
+}
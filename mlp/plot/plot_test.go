@@ -0,0 +1,119 @@
+package plot
+
+import (
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func TestPlotPNG(t *testing.T) {
+	m, history, inputs, labels := trainedXor(t)
+	dir := t.TempDir()
+
+	plots := map[string]func(string) error{
+		"history.png":  func(fpath string) error { return PlotHistory(history, fpath) },
+		"boundary.png": func(fpath string) error { return PlotDecisionBoundary(m, inputs, labels, fpath) },
+		"training.png": func(fpath string) error { return PlotTraining(history, m, inputs, labels, fpath) },
+	}
+	sizes := map[string][2]int{"history.png": {640, 640}, "boundary.png": {520, 520}, "training.png": {1160, 640}}
+
+	for name, plot := range plots {
+		fpath := filepath.Join(dir, name)
+		if err := plot(fpath); err != nil {
+			t.Fatalf("couldn't plot %s: %v", name, err)
+		}
+
+		f, err := os.Open(fpath)
+		if err != nil {
+			t.Fatalf("couldn't open %s: %v", name, err)
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s isn't a valid PNG: %v", name, err)
+		}
+		if b := img.Bounds(); b.Dx() != sizes[name][0] || b.Dy() != sizes[name][1] {
+			t.Errorf("wrong size for %s: %v", name, b)
+		}
+	}
+}
+
+func TestPlotSVG(t *testing.T) {
+	m, history, inputs, labels := trainedXor(t)
+	fpath := filepath.Join(t.TempDir(), "training.svg")
+	if err := PlotTraining(history, m, inputs, labels, fpath); err != nil {
+		t.Fatalf("PlotTraining() returned an error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		t.Fatalf("couldn't read the plot: %v", err)
+	}
+	svg := string(data)
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Fatalf("malformed SVG:\n%s", svg)
+	}
+	for _, text := range []string{">loss<", ">val_loss<", ">val_accuracy<", ">decision boundary<", ">class 0<", ">class 1<"} {
+		if !strings.Contains(svg, text) {
+			t.Errorf("the plot lacks %q", text)
+		}
+	}
+	if got := strings.Count(svg, "<circle"); got < len(inputs) {
+		t.Errorf("got %d circles for %d points", got, len(inputs))
+	}
+}
+
+func TestPlotErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := PlotHistory(&mlp.History{}, filepath.Join(dir, "history.jpg")); err == nil {
+		t.Errorf("PlotHistory() accepted an unknown format")
+	}
+
+	m, err := mlp.NewMlpWith([]int{3, 2, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	if err := PlotDecisionBoundary(m, [][]float64{{0, 0, 0}}, []float64{0}, filepath.Join(dir, "boundary.png")); err == nil {
+		t.Errorf("PlotDecisionBoundary() accepted 3-D inputs")
+	}
+}
+
+func TestPlotNegativeLabels(t *testing.T) {
+	m, err := mlp.NewMlpWith([]int{2, 2, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+	inputs, labels := [][]float64{{0, 0}, {1, 1}, {0, 1}}, []float64{-1, 1, -7}
+	if err := PlotDecisionBoundary(m, inputs, labels, filepath.Join(t.TempDir(), "boundary.png")); err != nil {
+		t.Errorf("PlotDecisionBoundary() returned an error: %v", err)
+	}
+}
+
+func TestPngCanvasText(t *testing.T) {
+	c := newPngCanvas(100, 40)
+	c.text(50, 20, "class 0", "middle")
+
+	w := textWidth("class 0", 2)
+	inked := func(x0, x1 int) int {
+		n := 0
+		for y := 0; y < 40; y++ {
+			for x := x0; x < x1; x++ {
+				if c.img.RGBAAt(x, y) == black {
+					n++
+				}
+			}
+		}
+		return n
+	}
+	if n := inked(50-w/2, 50+w/2+1); n == 0 {
+		t.Errorf("no text was drawn")
+	}
+	if n := inked(0, 50-w/2) + inked(50+w/2+1, 100); n != 0 {
+		t.Errorf("%d pixels were drawn outside the text", n)
+	}
+}
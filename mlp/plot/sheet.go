@@ -0,0 +1,102 @@
+package plot
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+// tileGap is the number of pixels between tiles, just like in the grids the
+// mlp package draws.
+const tileGap = 2
+
+var (
+	rightColor = color.RGBA{0, 140, 0, 255}
+	wrongColor = color.RGBA{200, 0, 0, 255}
+)
+
+// SheetOptions controls how a contact sheet is laid out.
+type SheetOptions struct {
+	// How each image is drawn
+	mlp.ImageOptions
+
+	// The range of images [From, To) on the sheet
+	From, To int
+
+	// Cols is the number of images on each row, 10 when 0.
+	Cols int
+
+	// Predicted holds the class predicted for each image on the sheet, if
+	// any. They're drawn next to the labels, if any, in green when right and
+	// in red when wrong.
+	Predicted []int
+}
+
+// DumpContactSheet writes a range of images as a single PNG grid with the
+// label of each image, if labels isn't nil, drawn beneath it.
+func DumpContactSheet(imgs mlp.Images, labels *mlp.Labels, fname string, opts SheetOptions) error {
+	n := opts.To - opts.From
+	if opts.From < 0 || n <= 0 || opts.To > len(imgs.Images) {
+		return fmt.Errorf("wrong image range [%d, %d) for %d images", opts.From, opts.To, len(imgs.Images))
+	}
+	if labels != nil && opts.To > len(labels.Labels) {
+		return fmt.Errorf("wrong image range [%d, %d) for %d labels", opts.From, opts.To, len(labels.Labels))
+	}
+	if opts.Predicted != nil && len(opts.Predicted) != n {
+		return fmt.Errorf("got %d predicted labels for %d images", len(opts.Predicted), n)
+	}
+	for _, p := range opts.Predicted {
+		if p < 0 {
+			return fmt.Errorf("wrong predicted label %d", p)
+		}
+	}
+	cols := opts.Cols
+	if cols <= 0 {
+		cols = 10
+	}
+	if n < cols {
+		cols = n
+	}
+
+	// Leave room for the labels beneath each tile
+	tileW, tileH := int(opts.Width), int(opts.Height)
+	scale, strip := 1+tileW/40, 0
+	if labels != nil || opts.Predicted != nil {
+		strip = 7 * scale
+	}
+	cellW, cellH := tileW+tileGap, tileH+strip+tileGap
+	rows := (n + cols - 1) / cols
+
+	sheet := image.NewRGBA(image.Rect(0, 0, cols*cellW+tileGap, rows*cellH+tileGap))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for i := 0; i < n; i++ {
+		img, err := imgs.Image(opts.From+i, opts.ImageOptions)
+		if err != nil {
+			return err
+		}
+		x, y := tileGap+(i%cols)*cellW, tileGap+(i/cols)*cellH
+		draw.Draw(sheet, image.Rect(x, y, x+tileW, y+tileH), img, img.Bounds().Min, draw.Src)
+
+		x, y = x+scale, y+tileH+scale
+		if labels != nil {
+			x += drawText(sheet, strconv.Itoa(int(labels.Labels[opts.From+i])), x, y, scale, color.Black) + 3*scale
+		}
+		if opts.Predicted != nil {
+			var c color.Color = color.Black
+			if labels != nil {
+				c = rightColor
+				if opts.Predicted[i] != int(labels.Labels[opts.From+i]) {
+					c = wrongColor
+				}
+			}
+			drawText(sheet, strconv.Itoa(opts.Predicted[i]), x, y, scale, c)
+		}
+	}
+
+	return writePNG(sheet, fname)
+}
@@ -0,0 +1,51 @@
+package plot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func TestDumpContactSheet(t *testing.T) {
+	imgs := smallImages(25, 4, 4)
+	labels := mlp.Labels{N: 25, Labels: make([]byte, 25)}
+	for i := range labels.Labels {
+		labels.Labels[i] = byte(i % 10)
+	}
+	fpath := filepath.Join(t.TempDir(), "sheet.png")
+
+	opts := SheetOptions{ImageOptions: mlp.ImageOptions{Width: 20, Height: 20, Interpolation: "bilinear"}, From: 3, To: 15, Cols: 5}
+	opts.Predicted = make([]int, 12)
+	if err := DumpContactSheet(imgs, &labels, fpath, opts); err != nil {
+		t.Fatalf("DumpContactSheet() returned an error: %v", err)
+	}
+	img := decodePNG(t, fpath)
+
+	// 12 images make up a 5 x 3 grid, each tile with a strip for its labels
+	cellW, cellH := 20+tileGap, 20+7+tileGap
+	if b := img.Bounds(); b.Dx() != 5*cellW+tileGap || b.Dy() != 3*cellH+tileGap {
+		t.Fatalf("wrong sheet size: %v", b)
+	}
+
+	// The first image is labelled 3, whose top row is solid, and predicted
+	// as 0 in red after a gap.
+	x, y := tileGap+1, tileGap+20+1
+	for dx := 0; dx < 3; dx++ {
+		if r, g, b, _ := img.At(x+dx, y).RGBA(); r != 0 || g != 0 || b != 0 {
+			t.Errorf("the label isn't drawn at (%d, %d)", x+dx, y)
+		}
+	}
+	if r, g, _, _ := img.At(x+6, y).RGBA(); uint8(r>>8) != wrongColor.R || uint8(g>>8) != wrongColor.G {
+		t.Errorf("the wrong prediction isn't drawn in red")
+	}
+
+	opts.To = 26
+	if err := DumpContactSheet(imgs, &labels, fpath, opts); err == nil {
+		t.Errorf("DumpContactSheet() accepted an out of range index")
+	}
+	opts.To, opts.Predicted = 15, []int{1}
+	if err := DumpContactSheet(imgs, nil, fpath, opts); err == nil {
+		t.Errorf("DumpContactSheet() accepted the wrong number of predictions")
+	}
+}
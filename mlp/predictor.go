@@ -0,0 +1,91 @@
+package mlp
+
+import (
+	"fmt"
+	"sync"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Predictor is an immutable snapshot of an MLP's parameters running in
+// inference mode. Unlike Mlp, it's safe to call its methods from several
+// goroutines at once, even while the MLP it was taken from keeps on training.
+type Predictor struct {
+	inDim, outDim int
+	seq           *Sequential
+
+	// Each concurrent caller borrows its own copy of the model sharing the
+	// snapshot's parameters.
+	pool sync.Pool
+}
+
+// Predictor takes a snapshot of the current parameters. Later changes to the
+// MLP won't be visible through the returned predictor: just take a new one to
+// pick them up.
+func (mlp *Mlp) Predictor() *Predictor {
+	p := Predictor{inDim: mlp.InDim, outDim: mlp.OutDim, seq: mlp.model().Clone(true).(*Sequential)}
+	p.pool.New = func() interface{} { return p.seq.Clone(false) }
+	return &p
+}
+
+func (p *Predictor) InDim() int {
+	return p.inDim
+}
+
+func (p *Predictor) OutDim() int {
+	return p.outDim
+}
+
+// Predict computes the MLP's output for input.
+func (p *Predictor) Predict(input []float64) ([]float64, error) {
+	if len(input) != p.inDim {
+		return nil, fmt.Errorf("wrong input dimension: got %d, expected %d", len(input), p.inDim)
+	}
+
+	outputs, _ := p.PredictBatch([][]float64{input})
+	return outputs[0], nil
+}
+
+// PredictBatch computes the MLP's output for each of the inputs.
+func (p *Predictor) PredictBatch(inputs [][]float64) ([][]float64, error) {
+	if len(inputs) == 0 {
+		return [][]float64{}, nil
+	}
+
+	x := mat.NewDense(len(inputs), p.inDim, nil)
+	for i, input := range inputs {
+		if len(input) != p.inDim {
+			return nil, fmt.Errorf("wrong dimension for input %d: got %d, expected %d", i, len(input), p.inDim)
+		}
+		copy(x.RawRowView(i), input)
+	}
+
+	seq := p.pool.Get().(*Sequential)
+	defer p.pool.Put(seq)
+
+	out := seq.Forward(x, false)
+
+	outputs := make([][]float64, len(inputs))
+	for i := range outputs {
+		outputs[i] = append([]float64(nil), out.RawRowView(i)...)
+	}
+	return outputs, nil
+}
+
+// ModelInfo describes a served model.
+type ModelInfo struct {
+	Dims       []int  `json:"dims"`
+	Activation string `json:"activation"`
+	Parameters int    `json:"parameters"`
+}
+
+// Info describes the shape of the MLP.
+func (mlp *Mlp) Info() ModelInfo {
+	dims := append([]int{mlp.InDim}, mlp.HiddenDim...)
+	info := ModelInfo{Dims: append(dims, mlp.OutDim), Activation: mlp.ActFunc.Name}
+	for _, p := range mlp.model().Params() {
+		r, c := p.Dims()
+		info.Parameters += r * c
+	}
+	return info
+}
@@ -0,0 +1,106 @@
+package mlp
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPredictorSnapshot(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	m.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+
+	p := m.Predictor()
+
+	output, _, _ := m.ComputeActivation([]float64{1, 0})
+	want := output[0]
+
+	for i := 0; i < 100; i++ {
+		m.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+	}
+
+	got, err := p.Predict([]float64{1, 0})
+	if err != nil {
+		t.Fatalf("Predict() returned an error: %v", err)
+	}
+	if got[0] != want {
+		t.Errorf("the snapshot changed after training: %6.3f != %6.3f", got[0], want)
+	}
+
+	batch, err := p.PredictBatch([][]float64{{1, 0}, {1, 0}})
+	if err != nil {
+		t.Fatalf("PredictBatch() returned an error: %v", err)
+	}
+	for i, out := range batch {
+		if out[0] != want {
+			t.Errorf("batch prediction %d mismatch: %6.3f != %6.3f", i, out[0], want)
+		}
+	}
+
+	if _, err := p.Predict([]float64{1, 0, 1}); err == nil {
+		t.Errorf("Predict() accepted an input of the wrong dimension")
+	}
+	if _, err := p.PredictBatch([][]float64{{1, 0}, {1}}); err == nil {
+		t.Errorf("PredictBatch() accepted an input of the wrong dimension")
+	}
+}
+
+// Run with -race to check training and swapping snapshots don't interfere
+// with concurrent predictions.
+func TestPredictorConcurrent(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 4, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	xorData, xorLabels := GenXor(200, 0.1)
+
+	var current atomic.Value
+	current.Store(m.Predictor())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, err := current.Load().(*Predictor).PredictBatch(xorData[:10]); err != nil {
+					t.Errorf("PredictBatch() returned an error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i, dp := range xorData {
+		m.Adapt(dp, xorLabels[i:i+1], 0.05)
+		if i%20 == 0 {
+			current.Store(m.Predictor())
+		}
+	}
+
+	wg.Wait()
+}
+
+func BenchmarkPredictParallel(b *testing.B) {
+	m, err := NewMlpWith([]int{2, 8, 8, 1}, SigmoidAct, 1)
+	if err != nil {
+		b.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	p := m.Predictor()
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		input := []float64{1, 0}
+		for pb.Next() {
+			if _, err := p.Predict(input); err != nil {
+				b.Fatalf("Predict() returned an error: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package mlp
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Regularization configures the penalties on the weights added to the loss.
+// Setting both L1 and L2 yields the elastic-net penalty:
+//
+//	L1 * sum(|w|) + L2 / 2 * sum(w^2)
+//
+// When MaxNorm is positive, the L2 norm of the weights feeding each neuron is
+// rescaled so that it's at most MaxNorm after every update. If ExcludeBias is
+// set, biases are neither penalised nor constrained.
+type Regularization struct {
+	L1          float64
+	L2          float64
+	MaxNorm     float64
+	ExcludeBias bool
+}
+
+// penalty returns the regularization term of the loss.
+func (r *Regularization) penalty(weights []*mat.Dense) float64 {
+	if r.L1 == 0 && r.L2 == 0 {
+		return 0
+	}
+
+	l1, l2 := 0.0, 0.0
+	for _, w := range weights {
+		_, c := w.Dims()
+		for j, v := range w.RawMatrix().Data {
+			if r.ExcludeBias && j%c == c-1 {
+				continue
+			}
+			l1 += math.Abs(v)
+			l2 += v * v
+		}
+	}
+	return r.L1*l1 + r.L2*l2/2
+}
+
+// grad returns the derivative of the penalty with respect to weight v.
+func (r *Regularization) grad(v float64) float64 {
+	g := r.L2 * v
+	if v > 0 {
+		g += r.L1
+	} else if v < 0 {
+		g -= r.L1
+	}
+	return g
+}
+
+// addGrad adds the derivative of the penalty with respect to the weights w to
+// grad.
+func (r *Regularization) addGrad(grad, w *mat.Dense) {
+	_, c := w.Dims()
+	gData := grad.RawMatrix().Data
+	for j, v := range w.RawMatrix().Data {
+		if !r.ExcludeBias || j%c != c-1 {
+			gData[j] += r.grad(v)
+		}
+	}
+}
+
+// decay applies the gradient descent step for the penalty on w.
+func (r *Regularization) decay(w *mat.Dense, learning_rate float64) {
+	if r.L1 == 0 && r.L2 == 0 {
+		return
+	}
+
+	_, c := w.Dims()
+	data := w.RawMatrix().Data
+	for j, v := range data {
+		if r.ExcludeBias && j%c == c-1 {
+			continue
+		}
+		data[j] -= learning_rate * r.grad(v)
+	}
+}
+
+// constrain enforces the max-norm constraint on each row of w.
+func (r *Regularization) constrain(w *mat.Dense) {
+	if r.MaxNorm <= 0 {
+		return
+	}
+
+	_, c := w.Dims()
+	if r.ExcludeBias {
+		c--
+	}
+
+	rows, _ := w.Dims()
+	for i := 0; i < rows; i++ {
+		row := w.RawRowView(i)[:c]
+
+		norm := 0.0
+		for _, v := range row {
+			norm += v * v
+		}
+		norm = math.Sqrt(norm)
+
+		if norm > r.MaxNorm {
+			for j := range row {
+				row[j] *= r.MaxNorm / norm
+			}
+		}
+	}
+}
+
+// Loss computes the mean squared error loss over the given samples plus the
+// regularization penalty, which is what training minimises. The samples go
+// through the MLP in inference mode.
+func (mlp *Mlp) Loss(inputs, targets [][]float64) float64 {
+	if len(inputs) == 0 {
+		return mlp.Regularization.penalty(mlp.Weights)
+	}
+	return mlp.loss(mlp.forwardBatch(mlp.model(), inputs, false), targets)
+}
+
+// loss computes the loss for the outputs of a forward pass, one per row.
+func (mlp *Mlp) loss(out *mat.Dense, targets [][]float64) float64 {
+	loss := 0.0
+	for s, target := range targets {
+		loss += squaredError(out.RawRowView(s), target)
+	}
+	return loss/float64(len(targets)) + mlp.Regularization.penalty(mlp.Weights)
+}
@@ -0,0 +1,100 @@
+package mlp
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestWeightDecay(t *testing.T) {
+	// The unit step's derivative is 0: the penalty is all that's left to
+	// drive the updates.
+	m, err := NewMlpWith([]int{2, 2, 1}, UnitStepAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	m.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+	m.Regularization = Regularization{L1: 0.5, L2: 0.1, ExcludeBias: true}
+
+	m.Adapt([]float64{1, 0}, []float64{1}, 0.5)
+
+	// w - 0.5 * (0.1 * w + 0.5 * sign(w)) for every weight but the biases
+	want := []*mat.Dense{
+		mat.NewDense(2, 3, []float64{5.45, 0, -2, 1.65, -1.65, 0}),
+		mat.NewDense(1, 3, []float64{-3.55, 1.65, 2}),
+	}
+	for i, w := range m.Weights {
+		if !mat.EqualApprox(w, want[i], 1e-12) {
+			t.Errorf("weight matrix %d mismatch after decaying: %6.3f != %6.3f",
+				i, mat.Formatted(w, mat.FormatMATLAB()), mat.Formatted(want[i], mat.FormatMATLAB()))
+		}
+	}
+}
+
+func TestRegularizedLoss(t *testing.T) {
+	m, err := NewMlpWith([]int{2, 2, 1}, SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("NewMlpWith() returned an error: %v", err)
+	}
+
+	m.SetWeights([][]float64{{6, 0, -2, 2, -2, 0}, {-4, 2, 2}})
+
+	inputs, targets := [][]float64{{1, 0}, {0, 1}}, [][]float64{{1}, {1}}
+	plain := m.Loss(inputs, targets)
+
+	m.Regularization = Regularization{L1: 0.5, L2: 0.1}
+	if got, want := m.Loss(inputs, targets)-plain, 0.5*20+0.1*(36+4+4+4+16+4+4)/2; math.Abs(got-want) > 1e-12 {
+		t.Errorf("wrong elastic-net penalty: %g != %g", got, want)
+	}
+
+	m.Regularization.ExcludeBias = true
+	if got, want := m.Loss(inputs, targets)-plain, 0.5*16+0.1*(36+4+4+16+4)/2; math.Abs(got-want) > 1e-12 {
+		t.Errorf("wrong elastic-net penalty without biases: %g != %g", got, want)
+	}
+}
+
+func TestMaxNorm(t *testing.T) {
+	for _, excludeBias := range []bool{false, true} {
+		m, err := NewMlpWith([]int{2, 6, 1}, SigmoidAct, 100)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		m.Regularization = Regularization{MaxNorm: 1.5, ExcludeBias: excludeBias}
+
+		m.AdaptBatch([][]float64{{1, 0}, {0, 1}}, [][]float64{{1}, {1}}, 10)
+
+		for i, w := range m.Weights {
+			r, c := w.Dims()
+			if excludeBias {
+				c--
+			}
+			for j := 0; j < r; j++ {
+				if norm := mat.Norm(w.Slice(j, j+1, 0, c), 2); norm > 1.5+1e-12 {
+					t.Errorf("row %d of weight matrix %d has norm %g with bias exclusion %t", j, i, norm, excludeBias)
+				}
+			}
+		}
+	}
+}
+
+func TestGradCheckRegularized(t *testing.T) {
+	regs := []Regularization{{L2: 0.3}, {L1: 0.2}, {L1: 0.2, L2: 0.3}, {L1: 0.2, L2: 0.3, ExcludeBias: true}}
+
+	for _, reg := range regs {
+		m, err := NewMlpWith([]int{3, 4, 2}, SigmoidAct, 1)
+		if err != nil {
+			t.Fatalf("NewMlpWith() returned an error: %v", err)
+		}
+		m.Regularization = reg
+
+		randomiseWeights(m, 1)
+
+		for l, relErr := range GradCheck(m, []float64{0.3, -1, 0.5}, []float64{1, 0}, 1e-6) {
+			if max := mat.Max(relErr); max > 1e-5 {
+				t.Errorf("gradient mismatch for weight matrix %d with %+v: max relative error %g", l, reg, max)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package mlp
+
+import (
+	"fmt"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Residual adds the input of Block to its output, letting gradients skip
+// over it, which is what makes training deep models practical. When the block
+// changes the dimension, the input goes through the linear Projection first.
+// Otherwise, Projection can be nil.
+type Residual struct {
+	Block      Layer
+	Projection *Dense
+
+	y, dx *mat.Dense
+}
+
+func NewResidual(block Layer, projection *Dense) *Residual {
+	return &Residual{Block: block, Projection: projection}
+}
+
+func (r *Residual) Forward(x *mat.Dense, training bool) *mat.Dense {
+	skip := x
+	if r.Projection != nil {
+		skip = r.Projection.Forward(x, training)
+	}
+	out := r.Block.Forward(x, training)
+
+	rows, cols := out.Dims()
+	if sr, sc := skip.Dims(); sr != rows || sc != cols {
+		panic(mat.ErrShape)
+	}
+
+	r.y = reuse(r.y, rows, cols)
+	r.y.Add(out, skip)
+	return r.y
+}
+
+func (r *Residual) Backward(dy *mat.Dense) *mat.Dense {
+	dBlock := r.Block.Backward(dy)
+	dSkip := dy
+	if r.Projection != nil {
+		dSkip = r.Projection.Backward(dy)
+	}
+
+	rows, cols := dBlock.Dims()
+	r.dx = reuse(r.dx, rows, cols)
+	r.dx.Add(dBlock, dSkip)
+	return r.dx
+}
+
+func (r *Residual) Params() []*mat.Dense {
+	params := r.Block.Params()
+	if r.Projection != nil {
+		params = append(append([]*mat.Dense(nil), params...), r.Projection.Params()...)
+	}
+	return params
+}
+
+func (r *Residual) Grads() []*mat.Dense {
+	grads := r.Block.Grads()
+	if r.Projection != nil {
+		grads = append(append([]*mat.Dense(nil), grads...), r.Projection.Grads()...)
+	}
+	return grads
+}
+
+func (r *Residual) Clone(deep bool) Layer {
+	c := Residual{Block: r.Block.Clone(deep)}
+	if r.Projection != nil {
+		c.Projection = r.Projection.Clone(deep).(*Dense)
+	}
+	return &c
+}
+
+func (r *Residual) SetWorkers(workers int) {
+	if p, ok := r.Block.(interface{ SetWorkers(int) }); ok {
+		p.SetWorkers(workers)
+	}
+	if r.Projection != nil {
+		r.Projection.SetWorkers(workers)
+	}
+}
+
+func (r *Residual) String() string {
+	msg := "Residual\n" + indent(describe(r.Block))
+	if r.Projection != nil {
+		msg += "\n" + indent("Projection: "+describe(r.Projection))
+	}
+	return msg
+}
+
+// describe summarises a layer, falling back to its type for those which
+// don't implement fmt.Stringer.
+func describe(l Layer) string {
+	if s, ok := l.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", l)
+}
+
+func indent(s string) string {
+	return "\t" + strings.ReplaceAll(s, "\n", "\n\t")
+}
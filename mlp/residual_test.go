@@ -0,0 +1,153 @@
+package mlp
+
+import (
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// checkLayerGrads compares the gradients l computes with central finite
+// differences of the loss sum(l.Forward(x) .* dy) for both the parameters and
+// the input.
+func checkLayerGrads(t *testing.T, l Layer, x, dy *mat.Dense) {
+	t.Helper()
+
+	loss := func() float64 {
+		out := new(mat.Dense)
+		out.MulElem(l.Forward(x, true), dy)
+		return mat.Sum(out)
+	}
+
+	l.Forward(x, true)
+	analytic := []*mat.Dense{mat.DenseCopyOf(l.Backward(dy))}
+	for _, g := range l.Grads() {
+		analytic = append(analytic, mat.DenseCopyOf(g))
+	}
+
+	eps := 1e-6
+	for i, p := range append([]*mat.Dense{x}, l.Params()...) {
+		raw := p.RawMatrix().Data
+		for k, orig := range raw {
+			raw[k] = orig + eps
+			lossPlus := loss()
+			raw[k] = orig - eps
+			lossMinus := loss()
+			raw[k] = orig
+
+			if relErr := relativeError(analytic[i].RawMatrix().Data[k], (lossPlus-lossMinus)/(2*eps)); relErr > 1e-5 {
+				t.Errorf("gradient mismatch for entry %d of parameter %d: relative error %g", k, i-1, relErr)
+			}
+		}
+	}
+}
+
+func TestResidualGradients(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	t.Run("identity", func(t *testing.T) {
+		block := NewSequential(NewDense(4, 4, 1, rng), NewActivationLayer(SigmoidAct), NewDense(4, 4, 1, rng))
+		checkLayerGrads(t, NewResidual(block, nil), randomDense(rng, 3, 4), randomDense(rng, 3, 4))
+	})
+
+	t.Run("projection", func(t *testing.T) {
+		block := NewSequential(NewDense(4, 6, 1, rng), NewNorm(LayerNorm, 6), NewActivationLayer(SigmoidAct))
+		checkLayerGrads(t, NewResidual(block, NewDense(4, 6, 1, rng)), randomDense(rng, 3, 4), randomDense(rng, 3, 6))
+	})
+}
+
+func TestDeepResidualTraining(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// 12 dense layers: the input one, 5 residual blocks holding 2 each and
+	// the output one.
+	seq := NewSequential(NewDense(2, 8, 0.5, rng), NewActivationLayer(ReLuAct))
+	for i := 0; i < 5; i++ {
+		block := NewSequential(NewDense(8, 8, 0.05, rng), NewActivationLayer(ReLuAct), NewDense(8, 8, 0.05, rng))
+		seq.Layers = append(seq.Layers, NewResidual(block, nil), NewActivationLayer(ReLuAct))
+	}
+	seq.Layers = append(seq.Layers, NewDense(8, 1, 0.1, rng), NewActivationLayer(SigmoidAct))
+
+	xorData, xorLabels := GenXor(200, 0.1)
+	x, targets := mat.NewDense(len(xorData), 2, nil), mat.NewDense(len(xorData), 1, xorLabels)
+	for i, input := range xorData {
+		x.SetRow(i, input)
+	}
+
+	for e := 0; e < 500; e++ {
+		dy := new(mat.Dense)
+		dy.Sub(seq.Forward(x, true), targets)
+		dy.Scale(1/float64(len(xorData)), dy)
+		seq.Backward(dy)
+		seq.Update(0.5)
+	}
+
+	out, errs := seq.Forward(x, false), 0
+	for i, l := range xorLabels {
+		if (out.At(i, 0) > 0.5) != (l > 0.5) {
+			errs++
+		}
+	}
+	if rate := float64(errs) / float64(len(xorLabels)); rate > 0.1 {
+		t.Errorf("error rate too high: %g", rate)
+	}
+}
+
+func TestSequentialSaveLoad(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	norm := NewNorm(BatchNorm, 6)
+	for j := range norm.RunningMean {
+		norm.RunningMean[j], norm.RunningVar[j] = rng.NormFloat64(), 0.5+rng.Float64()
+	}
+	block := NewSequential(NewDense(4, 6, 1, rng), norm, NewActivationLayer(ReLuAct), NewDropout(0.3, rng))
+	seq := NewSequential(
+		NewResidual(block, NewDense(4, 6, 1, rng)),
+		NewResidual(NewSequential(NewDense(6, 6, 1, rng), NewActivationLayer(SigmoidAct)), nil),
+		NewDense(6, 2, 1, rng),
+	)
+
+	fpath := filepath.Join(t.TempDir(), "model.json")
+	if err := seq.Save(fpath); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+	restored, err := LoadSequential(fpath)
+	if err != nil {
+		t.Fatalf("LoadSequential() returned an error: %v", err)
+	}
+
+	if restored.String() != seq.String() {
+		t.Errorf("summary mismatch:\n%s\n!=\n%s", restored, seq)
+	}
+
+	x := randomDense(rng, 5, 4)
+	if got, want := restored.Forward(x, false), seq.Forward(x, false); !mat.Equal(got, want) {
+		t.Errorf("output mismatch: %6.3f != %6.3f", mat.Formatted(got, mat.FormatMATLAB()), mat.Formatted(want, mat.FormatMATLAB()))
+	}
+
+	if err := NewSequential(newScale(1)).Save(fpath); err == nil {
+		t.Errorf("Save() didn't fail for a custom layer")
+	}
+
+	norm.Eps = 0
+	if err := seq.Save(fpath); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+	if _, err := LoadSequential(fpath); err == nil || !strings.Contains(err.Error(), "positive epsilon") {
+		t.Errorf("LoadSequential() accepted a normalisation without a positive epsilon: %v", err)
+	}
+}
+
+func TestResidualString(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	seq := NewSequential(NewResidual(NewSequential(NewDense(2, 3, 1, rng)), NewDense(2, 3, 1, rng)))
+
+	summary := seq.String()
+	for _, want := range []string{"Sequential: 18 parameters", "Residual", "Dense(2 -> 3)", "Projection: Dense(2 -> 3)"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("%q is missing from the summary:\n%s", want, summary)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package serve
+
+import "github.com/pcolladosoto/mlp-go/mlp/metrics"
+
+// Metrics tracks the requests a server handles.
+type Metrics struct {
+	Requests    *metrics.Counter
+	Latency     *metrics.Histogram
+	Predictions *metrics.Counter
+}
+
+// NewMetrics registers the serving metrics with r.
+func NewMetrics(r *metrics.Registry) *Metrics {
+	return &Metrics{
+		Requests:    r.NewCounter("mlp_requests_total", "Requests handled by endpoint and status code.", "endpoint", "code"),
+		Latency:     r.NewHistogram("mlp_request_duration_seconds", "Time taken to handle each request by endpoint.", metrics.DefBuckets, "endpoint"),
+		Predictions: r.NewCounter("mlp_predictions_total", "Inputs predicted on."),
+	}
+}
+
+// ObserveRequest records a request to the endpoint that took the given number
+// of seconds.
+func (m *Metrics) ObserveRequest(endpoint, code string, seconds float64) {
+	m.Requests.Inc(endpoint, code)
+	m.Latency.Observe(seconds, endpoint)
+}
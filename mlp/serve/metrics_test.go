@@ -0,0 +1,66 @@
+package serve
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape fetches the metrics from url, returning the value of each series
+// keyed by its name and labels as they're written.
+func scrape(t *testing.T, url string) map[string]float64 {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("wrong content type: %q", ct)
+	}
+
+	series := map[string]float64{}
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		line := s.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("couldn't parse %q: %v", line, err)
+		}
+		series[line[:i]] = v
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("couldn't read the metrics: %v", err)
+	}
+	return series
+}
+
+func TestServerMetrics(t *testing.T) {
+	_, srv := newTestServer(t)
+
+	var resp PredictResponse
+	post(t, srv.URL+"/v1/predict", `{"input": [1, 2, 3]}`, &resp)
+	var batch BatchPredictResponse
+	post(t, srv.URL+"/v1/predict/batch", `{"inputs": [[1, 2, 3], [4, 5, 6]]}`, &batch)
+	var errResp errorResponse
+	post(t, srv.URL+"/v1/predict", `{"input": [1]}`, &errResp)
+
+	series := scrape(t, srv.URL+"/metrics")
+	for name, want := range map[string]float64{
+		`mlp_requests_total{endpoint="/v1/predict",code="200"}`:                 1,
+		`mlp_requests_total{endpoint="/v1/predict",code="400"}`:                 1,
+		`mlp_requests_total{endpoint="/v1/predict/batch",code="200"}`:           1,
+		`mlp_request_duration_seconds_count{endpoint="/v1/predict"}`:            2,
+		`mlp_request_duration_seconds_bucket{endpoint="/v1/predict",le="+Inf"}`: 2,
+		`mlp_predictions_total`: 3,
+	} {
+		if got, ok := series[name]; !ok || got != want {
+			t.Errorf("wrong value for %s: %v, expected %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Package serve serves the predictions of MLPs as JSON over HTTP, swapping in
+// retrained models without downtime.
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+	"github.com/pcolladosoto/mlp-go/mlp/metrics"
+)
+
+type PredictRequest struct {
+	Input []float64 `json:"input"`
+}
+
+type PredictResponse struct {
+	Output []float64 `json:"output"`
+	Class  int       `json:"class"`
+}
+
+type BatchPredictRequest struct {
+	Inputs [][]float64 `json:"inputs"`
+}
+
+type BatchPredictResponse struct {
+	Outputs [][]float64 `json:"outputs"`
+	Classes []int       `json:"classes"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the predictions of an MLP as JSON over HTTP:
+//
+//	POST /v1/predict        takes a PredictRequest and returns a PredictResponse
+//	POST /v1/predict/batch  takes a BatchPredictRequest and returns a BatchPredictResponse
+//	GET  /v1/model          returns the mlp.ModelInfo
+//	GET  /healthz           returns {"status": "ok"}
+//	POST /v1/reload         reloads the model from ModelPath and returns its mlp.ModelInfo
+//	GET  /metrics           returns the metrics in Registry in the Prometheus format
+//
+// Failed requests get an error status together with {"error": "<message>"}.
+type Server struct {
+	// MaxBodyBytes bounds the size of request bodies and MaxBatch the number
+	// of inputs in batch requests.
+	MaxBodyBytes int64
+	MaxBatch     int
+
+	// ShutdownTimeout bounds how long Serve waits for in-flight requests once
+	// its context is done.
+	ShutdownTimeout time.Duration
+
+	// ModelPath is the file POST /v1/reload loads the model from. Reloading
+	// is disabled when it's empty.
+	ModelPath string
+
+	// Registry holds the Metrics of the server, to which other metrics can
+	// be added.
+	Registry *metrics.Registry
+
+	store   *ModelStore
+	metrics *Metrics
+	mux     *http.ServeMux
+}
+
+// NewServer serves a snapshot of the MLP taken through Predictor.
+func NewServer(m *mlp.Mlp) *Server {
+	return NewStoreServer(NewModelStore(m))
+}
+
+// NewStoreServer serves the model in the store, picking up the new ones
+// swapped in.
+func NewStoreServer(store *ModelStore) *Server {
+	s := Server{
+		MaxBodyBytes: 1 << 20, MaxBatch: 1024, ShutdownTimeout: 10 * time.Second,
+		Registry: metrics.NewRegistry(), store: store, mux: http.NewServeMux(),
+	}
+	s.metrics = NewMetrics(s.Registry)
+
+	s.handle("/v1/predict", s.handlePredict)
+	s.handle("/v1/predict/batch", s.handleBatchPredict)
+	s.handle("/v1/model", s.handleModel)
+	s.handle("/healthz", s.handleHealth)
+	s.handle("/v1/reload", s.handleReload)
+	s.mux.Handle("/metrics", s.Registry)
+	return &s
+}
+
+// Metrics returns the metrics tracking the requests to the server, which can
+// be shared with other servers of the same model.
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+// handle registers the handler for the endpoint, tracking its requests.
+func (s *Server) handle(endpoint string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(endpoint, func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := statusWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(&sw, r)
+		s.metrics.ObserveRequest(endpoint, strconv.Itoa(sw.status), time.Since(start).Seconds())
+	})
+}
+
+// statusWriter remembers the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Store returns the store holding the served model.
+func (s *Server) Store() *ModelStore {
+	return s.store
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Serve handles the connections accepted on l until ctx is done, shutting
+// down gracefully by waiting for in-flight requests to finish.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	srv := http.Server{Handler: s}
+
+	errs := make(chan error, 1)
+	go func() { errs <- srv.Serve(l) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ListenAndServe listens on the TCP address and calls Serve.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, l)
+}
+
+func (s *Server) handlePredict(w http.ResponseWriter, r *http.Request) {
+	var req PredictRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+
+	predictor, _ := s.store.Get()
+	output, err := predictor.Predict(req.Input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.metrics.Predictions.Inc()
+	writeJSON(w, http.StatusOK, PredictResponse{Output: output, Class: mlp.PredictedClass(output)})
+}
+
+func (s *Server) handleBatchPredict(w http.ResponseWriter, r *http.Request) {
+	var req BatchPredictRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if s.MaxBatch > 0 && len(req.Inputs) > s.MaxBatch {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("too many inputs: got %d, at most %d", len(req.Inputs), s.MaxBatch))
+		return
+	}
+
+	predictor, _ := s.store.Get()
+	outputs, err := predictor.PredictBatch(req.Inputs)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.metrics.Predictions.Add(float64(len(outputs)))
+	resp := BatchPredictResponse{Outputs: outputs, Classes: make([]int, len(outputs))}
+	for i, output := range outputs {
+		resp.Classes[i] = mlp.PredictedClass(output)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleModel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	_, info := s.store.Get()
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if s.ModelPath == "" {
+		writeError(w, http.StatusNotFound, "reloading is disabled")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	if err := s.store.Reload(s.ModelPath); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("kept the current model: %v", err))
+		return
+	}
+	_, info := s.store.Get()
+	writeJSON(w, http.StatusOK, info)
+}
+
+// decode reads the JSON body of a POST request into v, replying with an error
+// and returning false if it can't.
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return false
+	}
+
+	// MaxBytesReader also closes the connection once the limit is hit instead
+	// of reading the rest of the body
+	body := r.Body
+	if s.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.MaxBodyBytes)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil && s.MaxBodyBytes > 0 && int64(len(data)) == s.MaxBodyBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the request is larger than %d bytes", s.MaxBodyBytes))
+		return false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("couldn't read the request: %v", err))
+		return false
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("couldn't decode the request: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
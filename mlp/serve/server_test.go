@@ -0,0 +1,164 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func newTestServer(t *testing.T) (*mlp.Mlp, *httptest.Server) {
+	m, err := mlp.NewMlpWith([]int{3, 4, 2}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("mlp.NewMlpWith() returned an error: %v", err)
+	}
+	srv := httptest.NewServer(NewServer(m))
+	t.Cleanup(srv.Close)
+	return m, srv
+}
+
+// post sends body to the path, decoding the response into v.
+func post(t *testing.T, url, body string, v interface{}) int {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("couldn't decode the response of %s: %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestServerPredict(t *testing.T) {
+	m, srv := newTestServer(t)
+	inputs := [][]float64{{0.1, 0.2, 0.3}, {-1, 0, 1}}
+
+	var resp PredictResponse
+	if status := post(t, srv.URL+"/v1/predict", `{"input": [0.1, 0.2, 0.3]}`, &resp); status != http.StatusOK {
+		t.Fatalf("wrong status: %d", status)
+	}
+	want, _, _ := m.ComputeActivation(inputs[0])
+	if !reflect.DeepEqual(resp.Output, want) || resp.Class != mlp.PredictedClass(want) {
+		t.Errorf("wrong prediction: %+v, expected %v", resp, want)
+	}
+
+	var batch BatchPredictResponse
+	if status := post(t, srv.URL+"/v1/predict/batch", `{"inputs": [[0.1, 0.2, 0.3], [-1, 0, 1]]}`, &batch); status != http.StatusOK {
+		t.Fatalf("wrong status: %d", status)
+	}
+	if len(batch.Outputs) != 2 || len(batch.Classes) != 2 {
+		t.Fatalf("wrong batch prediction: %+v", batch)
+	}
+	for i, input := range inputs {
+		want, _, _ := m.ComputeActivation(input)
+		if !reflect.DeepEqual(batch.Outputs[i], want) || batch.Classes[i] != mlp.PredictedClass(want) {
+			t.Errorf("wrong prediction for input %d: %v, expected %v", i, batch.Outputs[i], want)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, srv := newTestServer(t)
+
+	big := fmt.Sprintf(`{"input": [%s0]}`, strings.Repeat("0, ", 1<<19))
+	batch := fmt.Sprintf(`{"inputs": [%s[0, 0, 0]]}`, strings.Repeat("[0, 0, 0], ", 1024))
+
+	for _, tc := range []struct {
+		path, body string
+		status     int
+		msg        string
+	}{
+		{"/v1/predict", `{"input": [1, 2]}`, http.StatusBadRequest, "wrong input dimension: got 2, expected 3"},
+		{"/v1/predict/batch", `{"inputs": [[1, 2, 3], [1]]}`, http.StatusBadRequest, "wrong dimension for input 1"},
+		{"/v1/predict", `{"input": [1, 2`, http.StatusBadRequest, "couldn't decode"},
+		{"/v1/predict", big, http.StatusRequestEntityTooLarge, "larger than"},
+		{"/v1/predict/batch", batch, http.StatusRequestEntityTooLarge, "too many inputs"},
+	} {
+		var resp errorResponse
+		if status := post(t, srv.URL+tc.path, tc.body, &resp); status != tc.status || !strings.Contains(resp.Error, tc.msg) {
+			t.Errorf("wrong response for %s: %d %q, expected %d %q", tc.path, status, resp.Error, tc.status, tc.msg)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/predict")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("wrong status for a GET prediction: %d", resp.StatusCode)
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	_, srv := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/v1/model")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info mlp.ModelInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("couldn't decode the model info: %v", err)
+	}
+	if !reflect.DeepEqual(info.Dims, []int{3, 4, 2}) || info.Activation != "sigmoid" || info.Parameters != 4*4+2*5 {
+		t.Errorf("wrong model info: %+v", info)
+	}
+
+	health, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	health.Body.Close()
+	if health.StatusCode != http.StatusOK {
+		t.Errorf("wrong health status: %d", health.StatusCode)
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	m, err := mlp.NewMlpWith([]int{2, 2, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("mlp.NewMlpWith() returned an error: %v", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("couldn't listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewServer(m).Serve(ctx, l) }()
+
+	url := "http://" + l.Addr().String() + "/v1/predict"
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(`{"input": [0, 1]}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() returned an error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Serve() didn't return after cancelling its context")
+	}
+
+	if _, err := http.Post(url, "application/json", bytes.NewBufferString(`{"input": [0, 1]}`)); err == nil {
+		t.Errorf("the server kept serving after shutting down")
+	}
+}
@@ -0,0 +1,98 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+// servedModel is what a ModelStore swaps atomically.
+type servedModel struct {
+	predictor *mlp.Predictor
+	info      mlp.ModelInfo
+}
+
+// ModelStore holds the model being served, letting it be swapped for a new one
+// without disrupting the requests in flight: each of them keeps on using the
+// model it started with.
+type ModelStore struct {
+	v atomic.Value
+}
+
+func NewModelStore(m *mlp.Mlp) *ModelStore {
+	var s ModelStore
+	s.Swap(m)
+	return &s
+}
+
+// Get returns the model currently served.
+func (s *ModelStore) Get() (*mlp.Predictor, mlp.ModelInfo) {
+	m := s.v.Load().(*servedModel)
+	return m.predictor, m.info
+}
+
+// Swap starts serving a snapshot of the MLP.
+func (s *ModelStore) Swap(m *mlp.Mlp) {
+	s.v.Store(&servedModel{predictor: m.Predictor(), info: m.Info()})
+}
+
+// Reload loads an MLP saved with Save and swaps it in as long as it's valid:
+// its parameters must be finite and it must take and produce as many values as
+// the current one so that clients aren't broken. The current model is kept
+// otherwise, even if loading the file panics.
+func (s *ModelStore) Reload(fpath string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("couldn't load %s: %v", fpath, r)
+		}
+	}()
+
+	m, err := mlp.Load(fpath)
+	if err != nil {
+		return fmt.Errorf("couldn't load %s: %v", fpath, err)
+	}
+	if !m.Finite() {
+		return fmt.Errorf("%s holds non-finite parameters", fpath)
+	}
+
+	current, _ := s.Get()
+	if m.InDim != current.InDim() || m.OutDim != current.OutDim() {
+		return fmt.Errorf("%s maps %d inputs to %d outputs, but the current model maps %d to %d",
+			fpath, m.InDim, m.OutDim, current.InDim(), current.OutDim())
+	}
+
+	s.Swap(m)
+	return nil
+}
+
+// Watch checks the file every interval until ctx is done, calling Reload
+// whenever its modification time or size change. If reloaded isn't nil, it's
+// called with the result of each reload.
+func (s *ModelStore) Watch(ctx context.Context, fpath string, interval time.Duration, reloaded func(err error)) {
+	last, _ := os.Stat(fpath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(fpath)
+		if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+			continue
+		}
+		last = info
+
+		err = s.Reload(fpath)
+		if reloaded != nil {
+			reloaded(err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package serve
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pcolladosoto/mlp-go/mlp"
+)
+
+func saveMlp(t *testing.T, dims []int, fpath string) *mlp.Mlp {
+	m, err := mlp.NewMlpWith(dims, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("mlp.NewMlpWith() returned an error: %v", err)
+	}
+	if err := m.Save(fpath); err != nil {
+		t.Fatalf("Save() returned an error: %v", err)
+	}
+	return m
+}
+
+// serves checks whether the store serves the MLP.
+func serves(t *testing.T, store *ModelStore, m *mlp.Mlp) bool {
+	p, _ := store.Get()
+	input := []float64{0.3, -0.7}
+	got, err := p.Predict(input)
+	if err != nil {
+		t.Fatalf("Predict() returned an error: %v", err)
+	}
+	want, _, _ := m.ComputeActivation(input)
+	return reflect.DeepEqual(got, want)
+}
+
+func TestModelStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	old, fpath := saveMlp(t, []int{2, 3, 1}, filepath.Join(dir, "old.json")), filepath.Join(dir, "model.json")
+	store := NewModelStore(old)
+
+	m := saveMlp(t, []int{2, 5, 1}, fpath)
+	if err := store.Reload(fpath); err != nil {
+		t.Fatalf("Reload() returned an error: %v", err)
+	}
+	if !serves(t, store, m) {
+		t.Errorf("the store doesn't serve the reloaded MLP")
+	}
+	if _, info := store.Get(); info.Dims[1] != 5 {
+		t.Errorf("wrong info for the reloaded MLP: %+v", info)
+	}
+
+	// Invalid models are rejected, keeping the current one
+	saveMlp(t, []int{3, 5, 1}, fpath)
+	if err := store.Reload(fpath); err == nil || !strings.Contains(err.Error(), "maps 3 inputs to 1 outputs") {
+		t.Errorf("Reload() accepted an MLP with different dimensions: %v", err)
+	}
+	if err := ioutil.WriteFile(fpath, []byte(`{"Dims": [2, 1`), 0644); err != nil {
+		t.Fatalf("couldn't corrupt the model: %v", err)
+	}
+	if err := store.Reload(fpath); err == nil {
+		t.Errorf("Reload() accepted a corrupt file")
+	}
+	if err := ioutil.WriteFile(fpath, []byte(`{"Dims": [2, 0, 1], "Activation": "sigmoid", "Weights": [[], [1]]}`), 0644); err != nil {
+		t.Fatalf("couldn't corrupt the model: %v", err)
+	}
+	if err := store.Reload(fpath); err == nil {
+		t.Errorf("Reload() accepted an MLP with an empty layer")
+	}
+	if !serves(t, store, m) {
+		t.Errorf("the store didn't keep the MLP after failing to reload")
+	}
+}
+
+func TestModelStoreSwapWhileServing(t *testing.T) {
+	a, err := mlp.NewMlpWith([]int{2, 3, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("mlp.NewMlpWith() returned an error: %v", err)
+	}
+	b, err := mlp.NewMlpWith([]int{2, 4, 1}, mlp.SigmoidAct, 1)
+	if err != nil {
+		t.Fatalf("mlp.NewMlpWith() returned an error: %v", err)
+	}
+	wantA, _, _ := a.ComputeActivation([]float64{1, 1})
+	wantB, _, _ := b.ComputeActivation([]float64{1, 1})
+
+	store := NewModelStore(a)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				p, _ := store.Get()
+				got, err := p.Predict([]float64{1, 1})
+				if err != nil || (!reflect.DeepEqual(got, wantA) && !reflect.DeepEqual(got, wantB)) {
+					t.Errorf("got a prediction from neither MLP: %v %v", got, err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		if i%2 == 0 {
+			store.Swap(b)
+		} else {
+			store.Swap(a)
+		}
+	}
+	wg.Wait()
+}
+
+func TestModelStoreWatch(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "model.json")
+	store := NewModelStore(saveMlp(t, []int{2, 3, 1}, fpath))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	results := make(chan error, 10)
+	go store.Watch(ctx, fpath, 5*time.Millisecond, func(err error) { results <- err })
+
+	// Keep on bumping the modification time, as the watcher may have
+	// started after the MLP was saved and coarse filesystems could miss
+	// the change otherwise.
+	m := saveMlp(t, []int{2, 6, 1}, fpath)
+	timeout := time.After(5 * time.Second)
+	for bump := 1; ; bump++ {
+		later := time.Now().Add(time.Duration(bump) * time.Second)
+		if err := os.Chtimes(fpath, later, later); err != nil {
+			t.Fatalf("couldn't touch the model: %v", err)
+		}
+
+		select {
+		case err := <-results:
+			if err != nil {
+				t.Fatalf("the watched MLP wasn't reloaded: %v", err)
+			}
+		case <-time.After(20 * time.Millisecond):
+			continue
+		case <-timeout:
+			t.Fatalf("the watched MLP wasn't reloaded in time")
+		}
+		break
+	}
+	if !serves(t, store, m) {
+		t.Errorf("the store doesn't serve the watched MLP")
+	}
+}
+
+func TestServerReload(t *testing.T) {
+	fpath := filepath.Join(t.TempDir(), "model.json")
+	srv := NewServer(saveMlp(t, []int{2, 3, 1}, fpath))
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var resp errorResponse
+	if status := post(t, ts.URL+"/v1/reload", "", &resp); status != http.StatusNotFound {
+		t.Errorf("reloading wasn't disabled: %d %q", status, resp.Error)
+	}
+
+	srv.ModelPath = fpath
+	m := saveMlp(t, []int{2, 7, 1}, fpath)
+	var info mlp.ModelInfo
+	if status := post(t, ts.URL+"/v1/reload", "", &info); status != http.StatusOK || info.Dims[1] != 7 {
+		t.Errorf("wrong reload response: %d %+v", status, info)
+	}
+	if !serves(t, srv.Store(), m) {
+		t.Errorf("the server doesn't serve the reloaded MLP")
+	}
+
+	saveMlp(t, []int{2, 7, 3}, fpath)
+	if status := post(t, ts.URL+"/v1/reload", "", &resp); status != http.StatusUnprocessableEntity || !strings.Contains(resp.Error, "kept the current model") {
+		t.Errorf("wrong response for an invalid model: %d %q", status, resp.Error)
+	}
+	if !serves(t, srv.Store(), m) {
+		t.Errorf("the server didn't keep the MLP after failing to reload")
+	}
+}